@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// signinFrom fails a sign-in claiming the client address in X-Forwarded-For
+func signinFrom(h *harness, username, forwardedFor string) *response {
+	h.t.Helper()
+
+	body, _ := json.Marshal(map[string]string{"username": username, "password": "Wrong123"})
+	req, err := http.NewRequest(http.MethodPost, h.server.URL+"/api/v1/auth/signin", bytes.NewReader(body))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	return h.send(req, "")
+}
+
+func TestLockoutIgnoresUntrustedForwardedFor(t *testing.T) {
+	h := newHarness(t, "--auth-lockout-threshold", "100", "--auth-lockout-ip-threshold", "2")
+
+	// rotating the claimed address does not escape the lockout of the peer address
+	signinFrom(h, "a@example.com", "203.0.113.1").status(http.StatusNotFound)
+	signinFrom(h, "b@example.com", "203.0.113.2").status(http.StatusTooManyRequests)
+	signinFrom(h, "c@example.com", "203.0.113.3").status(http.StatusTooManyRequests)
+}
+
+func TestLockoutTrustedProxy(t *testing.T) {
+	h := newHarness(t, "--auth-lockout-threshold", "100", "--auth-lockout-ip-threshold", "2",
+		"--server-trusted-proxies", "127.0.0.1")
+
+	// behind the trusted proxy the forwarded address is the client
+	signinFrom(h, "a@example.com", "203.0.113.1").status(http.StatusNotFound)
+	signinFrom(h, "b@example.com", "203.0.113.1").status(http.StatusTooManyRequests)
+	signinFrom(h, "c@example.com", "203.0.113.2").status(http.StatusNotFound)
+}
+
+func TestProxyForwardsResolvedClientIP(t *testing.T) {
+	forwarded := make(chan string, 1)
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the api fetches the published keys from the same address
+		if r.URL.Path == "/api/v1/auth/signin" {
+			forwarded <- r.Header.Get("X-Forwarded-For")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(auth.Close)
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "127.0.0.1"},
+		{[]string{"--server-trusted-proxies", "127.0.0.0/8"}, "203.0.113.1"},
+	} {
+		args := append([]string{"--auth-service-url", auth.URL, "--jwt-jwks-url", auth.URL + "/.well-known/jwks.json"}, tc.args...)
+		h := newHarness(t, args...)
+
+		signinFrom(h, "a@example.com", "198.51.100.1, 203.0.113.1").status(http.StatusNoContent)
+		if got := <-forwarded; got != tc.want {
+			t.Fatalf("%v: auth service got X-Forwarded-For %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
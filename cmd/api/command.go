@@ -2,9 +2,12 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -50,9 +53,62 @@ var (
 	defRedisClientName string = "bsp"
 	defRedisDb         int    = 0
 
-	defJwtSecret            string        = "7b22fce240c32115056ba109f035542a3a1f9e1ee62fa653fa0a4ec0e267eb15"
+	defJwtPrivateKey        string        = "cert/jwt.key"
+	defJwtIssuer            string        = "bsp"
+	defJwtAudience          string        = "bsp"
+	defJwtAllowedAlgorithms []string      = []string{"RS256", "EdDSA"}
+	defJwtLeeway            time.Duration = time.Duration(30 * time.Second)
 	defJwtAccessExpiration  time.Duration = time.Duration(15 * time.Minute)
 	defJwtRefreshExpiration time.Duration = time.Duration(24 * time.Hour)
+
+	defJwtJwksRefreshInterval time.Duration = time.Duration(1 * time.Minute)
+
+	defJwtChallengeExpiration time.Duration = time.Duration(5 * time.Minute)
+	defJwtVerifyExpiration    time.Duration = time.Duration(24 * time.Hour)
+
+	defMailerTransport string = "log"
+	defMailerFrom      string = "BSP <noreply@brickwall.com>"
+	defMailerFileDir   string = "tmp/mail"
+	defMailerSmtpAddr  string = "localhost:587"
+
+	defAuthVerifyUrl            string        = "http://localhost:8081/api/v1/auth/verify"
+	defAuthVerifyResendInterval time.Duration = time.Duration(1 * time.Minute)
+	defAuthResetUrl             string        = "http://localhost:8081/reset-password"
+	defAuthResetExpiration      time.Duration = time.Duration(1 * time.Hour)
+	defAuthResetInterval        time.Duration = time.Duration(1 * time.Minute)
+	defAuthMagicLinkEnabled     bool          = false
+	defAuthMagicLinkUrl         string        = "http://localhost:8081/magic-link"
+	defAuthMagicLinkExpiration  time.Duration = time.Duration(15 * time.Minute)
+	defAuthMagicLinkInterval    time.Duration = time.Duration(1 * time.Minute)
+	defAuthPrincipalCacheTTL    time.Duration = time.Duration(30 * time.Second)
+	defAuthPolicyCacheTTL       time.Duration = time.Duration(1 * time.Minute)
+	defAuthLockoutThreshold     int           = 5
+	defAuthLockoutIpThreshold   int           = 20
+	defAuthLockoutDuration      time.Duration = time.Duration(1 * time.Minute)
+	defAuthLockoutMaxDuration   time.Duration = time.Duration(1 * time.Hour)
+	defAuthLockoutWindow        time.Duration = time.Duration(24 * time.Hour)
+
+	defPasswordMinLength         int      = 8
+	defPasswordRequiredClasses   []string = []string{"lower", "upper", "digit"}
+	defPasswordHashAlgorithm     string   = "argon2id"
+	defPasswordArgon2Memory      int      = 19456
+	defPasswordArgon2Iterations  int      = 2
+	defPasswordArgon2Parallelism int      = 1
+	defPasswordBcryptCost        int      = 12
+
+	defOAuthCodeExpiration time.Duration = time.Duration(1 * time.Minute)
+
+	defOidcName            string        = "oidc"
+	defOidcScopes          []string      = []string{"openid", "email", "profile"}
+	defOidcRedirectUrl     string        = "http://localhost:8081/auth/oidc/callback"
+	defOidcStateExpiration time.Duration = time.Duration(10 * time.Minute)
+	defOidcTimeout         time.Duration = time.Duration(5 * time.Second)
+
+	defWebAuthnRPID        string        = "localhost"
+	defWebAuthnRPName      string        = "Brickwall"
+	defWebAuthnRPOrigins   []string      = []string{"http://localhost:8081"}
+	defWebAuthnUV          string        = "preferred"
+	defWebAuthnStateExpiry time.Duration = time.Duration(5 * time.Minute)
 )
 
 func Command(ctx context.Context) *cli.Command {
@@ -62,263 +118,685 @@ func Command(ctx context.Context) *cli.Command {
 		Usage:    "Run the api service",
 		Action: func(ctx context.Context, cli *cli.Command) error {
 			ctx = context.WithValue(ctx, common.KeyCommand, cli)
-			return bootstrap(ctx)
-		},
-		Flags: []cli.Flag{
-			//
-			// TLS/SSL section
-			//
-			&cli.BoolFlag{
-				Name:        "tls-ssl-enabled",
-				Usage:       "Server HTTPS SSL enabled or not",
-				Value:       defTlsSslEnabled,
-				DefaultText: strconv.FormatBool(defTlsSslEnabled),
-				Sources:     cli.EnvVars("TLS_SSL_ENABLED"),
-			},
-			&cli.StringFlag{
-				Name:        "tls-ssl-cert",
-				Usage:       "Server HTTPS SSL certificate cert",
-				Value:       defTlsSslCert,
-				DefaultText: defTlsSslCert,
-				Sources:     cli.EnvVars("TLS_SSL_CERT"),
-			},
-			&cli.StringFlag{
-				Name:        "tls-ssl-key",
-				Usage:       "Server HTTPS SSL certificate key",
-				Value:       defTlsSslKey,
-				DefaultText: defTlsSslKey,
-				Sources:     cli.EnvVars("TLS_SSL_KEY"),
-			},
-			//
-			// Cors section
-			//
-			&cli.BoolFlag{
-				Name:        "cors-enabled",
-				Usage:       "Server HTTP CORS enabled or not",
-				Value:       defCorsEnabled,
-				DefaultText: strconv.FormatBool(defCorsEnabled),
-				Sources:     cli.EnvVars("CORS_ENABLED"),
-			},
-			&cli.StringFlag{
-				Name:        "cors-allow-origin",
-				Usage:       "Server HTTP cors allowed origin",
-				Value:       defCorsAllowOrigin,
-				DefaultText: defCorsAllowOrigin,
-				Sources:     cli.EnvVars("CORS_ALLOW_ORIGIN"),
-			},
-			&cli.StringFlag{
-				Name:        "cors-allow-methods",
-				Usage:       "Server HTTP cors allow methods",
-				Value:       defCorsAllowMethods,
-				DefaultText: defCorsAllowMethods,
-				Sources:     cli.EnvVars("CORS_ALLOW_METHODS"),
-			},
-			&cli.StringFlag{
-				Name:        "cors-allow-headers",
-				Usage:       "Server HTTP cors allow headers",
-				Value:       defCorsAllowHeaders,
-				DefaultText: defCorsAllowHeaders,
-				Sources:     cli.EnvVars("CORS_ALLOW_HEADERS"),
-			},
-			&cli.BoolFlag{
-				Name:        "cors-allow-credentials",
-				Usage:       "Server HTTP cors allow credentials",
-				Value:       defCorsAllowCredentials,
-				DefaultText: strconv.FormatBool(defCorsAllowCredentials),
-				Sources:     cli.EnvVars("CORS_ALLOW_CREDENTIALS"),
-			},
-			&cli.StringFlag{
-				Name:        "cors-expose-headers",
-				Usage:       "Server HTTP cors expose headers",
-				Value:       defCorsExposeHeaders,
-				DefaultText: defCorsExposeHeaders,
-				Sources:     cli.EnvVars("CORS_EXPOSE_HEADERS"),
-			},
-			&cli.IntFlag{
-				Name:        "cors-max-age",
-				Usage:       "Server max header bytes",
-				Value:       int64(defCorsMaxAge),
-				DefaultText: strconv.FormatInt(int64(defCorsMaxAge), 10),
-				Sources:     cli.EnvVars("CORS_MAX_AGE"),
-			},
-			//
-			// Server section
-			//
-			&cli.StringFlag{
-				Name:        "server-address",
-				Usage:       "Server HTTP binding address",
-				Value:       defServerAddress,
-				DefaultText: defServerAddress,
-				Sources:     cli.EnvVars("SERVER_ADDRESS"),
-			},
-			&cli.DurationFlag{
-				Name:        "server-read-timeout",
-				Usage:       "Server read timeout",
-				Value:       defServerReadTimeout,
-				DefaultText: defServerReadTimeout.String(),
-				Sources:     cli.EnvVars("SERVER_READ_TIMEOUT"),
-			},
-			&cli.DurationFlag{
-				Name:        "server-write-timeout",
-				Usage:       "Server write timeout",
-				Value:       defServerWriteTimeout,
-				DefaultText: defServerWriteTimeout.String(),
-				Sources:     cli.EnvVars("SERVER_WRITE_TIMEOUT"),
-			},
-			&cli.DurationFlag{
-				Name:        "server-graceful-timeout",
-				Usage:       "Server shutdown graceful timeout",
-				Value:       defServerGracefulTimeout,
-				DefaultText: defServerGracefulTimeout.String(),
-				Sources:     cli.EnvVars("SERVER_GRACEFUL_TIMEOUT"),
-			},
-			&cli.IntFlag{
-				Name:        "server-max-header-bytes",
-				Usage:       "Server max header bytes",
-				Value:       int64(defServerMaxHeaderBytes),
-				DefaultText: strconv.FormatInt(int64(defServerMaxHeaderBytes), 10),
-				Sources:     cli.EnvVars("SERVER_MAX_HEADER_BYTES"),
-			},
-			//
-			// Postgres section
-			//
-			&cli.StringFlag{
-				Name:        "postgres-db",
-				Usage:       "Postgres database name",
-				Value:       defPostgresDb,
-				DefaultText: defPostgresDb,
-				Sources:     cli.EnvVars("POSTGRES_DB"),
-			},
-			&cli.StringFlag{
-				Name:        "postgres-host",
-				Usage:       "Postgres database host",
-				Value:       defPostgresHost,
-				DefaultText: defPostgresHost,
-				Sources:     cli.EnvVars("POSTGRES_HOST"),
-			},
-			&cli.IntFlag{
-				Name:        "postgres-port",
-				Usage:       "Postgres database port",
-				Value:       int64(defPostgresPort),
-				DefaultText: strconv.FormatInt(int64(defPostgresPort), 10),
-				Sources:     cli.EnvVars("POSTGRES_PORT"),
-			},
-			&cli.StringFlag{
-				Name:        "postgres-user",
-				Usage:       "Postgres database user",
-				Value:       defPostgresUser,
-				DefaultText: defPostgresUser,
-				Sources:     cli.EnvVars("POSTGRES_USER"),
-			},
-			&cli.StringFlag{
-				Name:        "postgres-password",
-				Usage:       "Postgres database password",
-				Value:       defPostgresPassword,
-				DefaultText: defPostgresPassword,
-				Sources:     cli.EnvVars("POSTGRES_PASSWORD"),
-			},
-			&cli.IntFlag{
-				Name:        "postgres-max-conns",
-				Usage:       "Postgres database max opened conns",
-				Value:       int64(defPostgresMaxConns),
-				DefaultText: strconv.FormatInt(int64(defPostgresMaxConns), 10),
-				Sources:     cli.EnvVars("POSTGRES_MAX_CONNS"),
-			},
-			&cli.IntFlag{
-				Name:        "postgres-min-conns",
-				Usage:       "Postgres database min opened conns",
-				Value:       int64(defPostgresMinConns),
-				DefaultText: strconv.FormatInt(int64(defPostgresMinConns), 10),
-				Sources:     cli.EnvVars("POSTGRES_MIN_CONNS"),
-			},
-			&cli.DurationFlag{
-				Name:        "postgres-max-conn-life-time",
-				Usage:       "Postgres database max conn life time",
-				Value:       defPostgresMaxConnLifeTime,
-				DefaultText: defPostgresMaxConnLifeTime.String(),
-				Sources:     cli.EnvVars("POSTGRES_MAX_CONN_LIFE_TIME"),
-			},
-			&cli.DurationFlag{
-				Name:        "postgres-max-conn-idle-time",
-				Usage:       "Postgres database max conn idle time",
-				Value:       defPostgresMaxConnIdleTime,
-				DefaultText: defPostgresMaxConnIdleTime.String(),
-				Sources:     cli.EnvVars("POSTGRES_MAX_CONN_IDLE_TIME"),
-			},
-			&cli.DurationFlag{
-				Name:        "postgres-health-check-period",
-				Usage:       "Postgres database health check period",
-				Value:       defPostgresHealthCheckPeriod,
-				DefaultText: defPostgresHealthCheckPeriod.String(),
-				Sources:     cli.EnvVars("POSTGRES_HEALTH_CHECK_PERIOD"),
-			},
-			//
-			// Redis section
-			//
-			&cli.StringFlag{
-				Name:        "redis-addr",
-				Usage:       "Redis server address",
-				Value:       defRedisAddr,
-				DefaultText: defRedisAddr,
-				Sources:     cli.EnvVars("REDIS_ADDR"),
-			},
-			&cli.StringFlag{
-				Name:        "redis-network",
-				Usage:       "Redis network type (tcp|unix)",
-				Value:       defRedisNetwork,
-				DefaultText: defRedisNetwork,
-				Sources:     cli.EnvVars("REDIS_NETWORK"),
-			},
-			&cli.StringFlag{
-				Name:        "redis-client-name",
-				Usage:       "Redis server client name",
-				Value:       defRedisClientName,
-				DefaultText: defRedisClientName,
-				Sources:     cli.EnvVars("REDIS_CLIENT_NAME"),
-			},
-			&cli.IntFlag{
-				Name:        "redis-db",
-				Usage:       "Redis database number",
-				Value:       int64(defRedisDb),
-				DefaultText: strconv.FormatInt(int64(defRedisDb), 10),
-				Sources:     cli.EnvVars("REDIS_DB"),
-			},
-			//
-			// Jwt section
-			//
+			if authServiceUrl := cli.String("auth-service-url"); authServiceUrl != "" {
+				if _, err := url.ParseRequestURI(authServiceUrl); err != nil {
+					return fmt.Errorf("%w: %v", common.ErrAuthServiceUrl, err)
+				}
+				// the api only verifies the tokens, the signing keys stay with the auth service
+				if cli.String("jwt-jwks-url") == "" {
+					return fmt.Errorf("%w: %v", common.ErrAuthServiceUrl, "jwt-jwks-url is required to verify the tokens of the auth service")
+				}
+			}
+			return Serve(ctx, RegisterRoutes)
+		},
+		Flags: append(Flags(defServerAddress),
 			&cli.StringFlag{
-				Name:        "jwt-secret",
-				Usage:       "Jwt secret 32 bytes string",
-				Value:       defJwtSecret,
-				DefaultText: "Jwt secret string (32 bytes)",
-				Sources:     cli.EnvVars("JWT_SECRET"),
-			},
-			&cli.DurationFlag{
-				Name:        "jwt-access-expiration",
-				Usage:       "Jwt access token expiration time",
-				Value:       defJwtAccessExpiration,
-				DefaultText: defJwtAccessExpiration.String(),
-				Sources:     cli.EnvVars("JWT_ACCESS_EXPIRATION"),
-			},
-			&cli.DurationFlag{
-				Name:        "jwt-refresh-expiration",
-				Usage:       "Jwt refresh token expiration time",
-				Value:       defJwtRefreshExpiration,
-				DefaultText: defJwtRefreshExpiration.String(),
-				Sources:     cli.EnvVars("JWT_REFRESH_EXPIRATION"),
+				Name:    "auth-service-url",
+				Usage:   "Auth service base url, the api forwards the auth routes and verifies tokens only, empty serves them itself",
+				Sources: cli.EnvVars("AUTH_SERVICE_URL"),
 			},
-		},
+		),
 	}
 
 	return command
 }
 
+// Flags are shared by the api and the auth services, each service binds its own
+// default address
+func Flags(serverAddress string) []cli.Flag {
+	return []cli.Flag{
+		//
+		// TLS/SSL section
+		//
+		&cli.BoolFlag{
+			Name:        "tls-ssl-enabled",
+			Usage:       "Server HTTPS SSL enabled or not",
+			Value:       defTlsSslEnabled,
+			DefaultText: strconv.FormatBool(defTlsSslEnabled),
+			Sources:     cli.EnvVars("TLS_SSL_ENABLED"),
+		},
+		&cli.StringFlag{
+			Name:        "tls-ssl-cert",
+			Usage:       "Server HTTPS SSL certificate cert",
+			Value:       defTlsSslCert,
+			DefaultText: defTlsSslCert,
+			Sources:     cli.EnvVars("TLS_SSL_CERT"),
+		},
+		&cli.StringFlag{
+			Name:        "tls-ssl-key",
+			Usage:       "Server HTTPS SSL certificate key",
+			Value:       defTlsSslKey,
+			DefaultText: defTlsSslKey,
+			Sources:     cli.EnvVars("TLS_SSL_KEY"),
+		},
+		//
+		// Cors section
+		//
+		&cli.BoolFlag{
+			Name:        "cors-enabled",
+			Usage:       "Server HTTP CORS enabled or not",
+			Value:       defCorsEnabled,
+			DefaultText: strconv.FormatBool(defCorsEnabled),
+			Sources:     cli.EnvVars("CORS_ENABLED"),
+		},
+		&cli.StringFlag{
+			Name:        "cors-allow-origin",
+			Usage:       "Server HTTP cors allowed origin",
+			Value:       defCorsAllowOrigin,
+			DefaultText: defCorsAllowOrigin,
+			Sources:     cli.EnvVars("CORS_ALLOW_ORIGIN"),
+		},
+		&cli.StringFlag{
+			Name:        "cors-allow-methods",
+			Usage:       "Server HTTP cors allow methods",
+			Value:       defCorsAllowMethods,
+			DefaultText: defCorsAllowMethods,
+			Sources:     cli.EnvVars("CORS_ALLOW_METHODS"),
+		},
+		&cli.StringFlag{
+			Name:        "cors-allow-headers",
+			Usage:       "Server HTTP cors allow headers",
+			Value:       defCorsAllowHeaders,
+			DefaultText: defCorsAllowHeaders,
+			Sources:     cli.EnvVars("CORS_ALLOW_HEADERS"),
+		},
+		&cli.BoolFlag{
+			Name:        "cors-allow-credentials",
+			Usage:       "Server HTTP cors allow credentials",
+			Value:       defCorsAllowCredentials,
+			DefaultText: strconv.FormatBool(defCorsAllowCredentials),
+			Sources:     cli.EnvVars("CORS_ALLOW_CREDENTIALS"),
+		},
+		&cli.StringFlag{
+			Name:        "cors-expose-headers",
+			Usage:       "Server HTTP cors expose headers",
+			Value:       defCorsExposeHeaders,
+			DefaultText: defCorsExposeHeaders,
+			Sources:     cli.EnvVars("CORS_EXPOSE_HEADERS"),
+		},
+		&cli.IntFlag{
+			Name:        "cors-max-age",
+			Usage:       "Server max header bytes",
+			Value:       int64(defCorsMaxAge),
+			DefaultText: strconv.FormatInt(int64(defCorsMaxAge), 10),
+			Sources:     cli.EnvVars("CORS_MAX_AGE"),
+		},
+		//
+		// Server section
+		//
+		&cli.StringFlag{
+			Name:        "server-address",
+			Usage:       "Server HTTP binding address",
+			Value:       defServerAddress,
+			DefaultText: defServerAddress,
+			Sources:     cli.EnvVars("SERVER_ADDRESS"),
+		},
+		&cli.DurationFlag{
+			Name:        "server-read-timeout",
+			Usage:       "Server read timeout",
+			Value:       defServerReadTimeout,
+			DefaultText: defServerReadTimeout.String(),
+			Sources:     cli.EnvVars("SERVER_READ_TIMEOUT"),
+		},
+		&cli.DurationFlag{
+			Name:        "server-write-timeout",
+			Usage:       "Server write timeout",
+			Value:       defServerWriteTimeout,
+			DefaultText: defServerWriteTimeout.String(),
+			Sources:     cli.EnvVars("SERVER_WRITE_TIMEOUT"),
+		},
+		&cli.DurationFlag{
+			Name:        "server-graceful-timeout",
+			Usage:       "Server shutdown graceful timeout",
+			Value:       defServerGracefulTimeout,
+			DefaultText: defServerGracefulTimeout.String(),
+			Sources:     cli.EnvVars("SERVER_GRACEFUL_TIMEOUT"),
+		},
+		&cli.IntFlag{
+			Name:        "server-max-header-bytes",
+			Usage:       "Server max header bytes",
+			Value:       int64(defServerMaxHeaderBytes),
+			DefaultText: strconv.FormatInt(int64(defServerMaxHeaderBytes), 10),
+			Sources:     cli.EnvVars("SERVER_MAX_HEADER_BYTES"),
+		},
+		&cli.StringSliceFlag{
+			Name:    "server-trusted-proxies",
+			Usage:   "Proxy addresses or CIDRs whose X-Forwarded-For is trusted, the client address is the peer address when empty",
+			Sources: cli.EnvVars("SERVER_TRUSTED_PROXIES"),
+		},
+		//
+		// Postgres section
+		//
+		&cli.StringFlag{
+			Name:        "postgres-db",
+			Usage:       "Postgres database name",
+			Value:       defPostgresDb,
+			DefaultText: defPostgresDb,
+			Sources:     cli.EnvVars("POSTGRES_DB"),
+		},
+		&cli.StringFlag{
+			Name:        "postgres-host",
+			Usage:       "Postgres database host",
+			Value:       defPostgresHost,
+			DefaultText: defPostgresHost,
+			Sources:     cli.EnvVars("POSTGRES_HOST"),
+		},
+		&cli.IntFlag{
+			Name:        "postgres-port",
+			Usage:       "Postgres database port",
+			Value:       int64(defPostgresPort),
+			DefaultText: strconv.FormatInt(int64(defPostgresPort), 10),
+			Sources:     cli.EnvVars("POSTGRES_PORT"),
+		},
+		&cli.StringFlag{
+			Name:        "postgres-user",
+			Usage:       "Postgres database user",
+			Value:       defPostgresUser,
+			DefaultText: defPostgresUser,
+			Sources:     cli.EnvVars("POSTGRES_USER"),
+		},
+		&cli.StringFlag{
+			Name:        "postgres-password",
+			Usage:       "Postgres database password",
+			Value:       defPostgresPassword,
+			DefaultText: defPostgresPassword,
+			Sources:     cli.EnvVars("POSTGRES_PASSWORD"),
+		},
+		&cli.IntFlag{
+			Name:        "postgres-max-conns",
+			Usage:       "Postgres database max opened conns",
+			Value:       int64(defPostgresMaxConns),
+			DefaultText: strconv.FormatInt(int64(defPostgresMaxConns), 10),
+			Sources:     cli.EnvVars("POSTGRES_MAX_CONNS"),
+		},
+		&cli.IntFlag{
+			Name:        "postgres-min-conns",
+			Usage:       "Postgres database min opened conns",
+			Value:       int64(defPostgresMinConns),
+			DefaultText: strconv.FormatInt(int64(defPostgresMinConns), 10),
+			Sources:     cli.EnvVars("POSTGRES_MIN_CONNS"),
+		},
+		&cli.DurationFlag{
+			Name:        "postgres-max-conn-life-time",
+			Usage:       "Postgres database max conn life time",
+			Value:       defPostgresMaxConnLifeTime,
+			DefaultText: defPostgresMaxConnLifeTime.String(),
+			Sources:     cli.EnvVars("POSTGRES_MAX_CONN_LIFE_TIME"),
+		},
+		&cli.DurationFlag{
+			Name:        "postgres-max-conn-idle-time",
+			Usage:       "Postgres database max conn idle time",
+			Value:       defPostgresMaxConnIdleTime,
+			DefaultText: defPostgresMaxConnIdleTime.String(),
+			Sources:     cli.EnvVars("POSTGRES_MAX_CONN_IDLE_TIME"),
+		},
+		&cli.DurationFlag{
+			Name:        "postgres-health-check-period",
+			Usage:       "Postgres database health check period",
+			Value:       defPostgresHealthCheckPeriod,
+			DefaultText: defPostgresHealthCheckPeriod.String(),
+			Sources:     cli.EnvVars("POSTGRES_HEALTH_CHECK_PERIOD"),
+		},
+		//
+		// Redis section
+		//
+		&cli.StringFlag{
+			Name:        "redis-addr",
+			Usage:       "Redis server address",
+			Value:       defRedisAddr,
+			DefaultText: defRedisAddr,
+			Sources:     cli.EnvVars("REDIS_ADDR"),
+		},
+		&cli.StringFlag{
+			Name:        "redis-network",
+			Usage:       "Redis network type (tcp|unix)",
+			Value:       defRedisNetwork,
+			DefaultText: defRedisNetwork,
+			Sources:     cli.EnvVars("REDIS_NETWORK"),
+		},
+		&cli.StringFlag{
+			Name:        "redis-client-name",
+			Usage:       "Redis server client name",
+			Value:       defRedisClientName,
+			DefaultText: defRedisClientName,
+			Sources:     cli.EnvVars("REDIS_CLIENT_NAME"),
+		},
+		&cli.IntFlag{
+			Name:        "redis-db",
+			Usage:       "Redis database number",
+			Value:       int64(defRedisDb),
+			DefaultText: strconv.FormatInt(int64(defRedisDb), 10),
+			Sources:     cli.EnvVars("REDIS_DB"),
+		},
+		//
+		// Jwt section
+		//
+		&cli.StringFlag{
+			Name:        "jwt-private-key",
+			Usage:       "Jwt signing private key PEM file (RSA or Ed25519)",
+			Value:       defJwtPrivateKey,
+			DefaultText: defJwtPrivateKey,
+			Sources:     cli.EnvVars("JWT_PRIVATE_KEY"),
+		},
+		&cli.StringSliceFlag{
+			Name:    "jwt-public-keys",
+			Usage:   "Jwt additional verification key PEM files (rotated keys)",
+			Sources: cli.EnvVars("JWT_PUBLIC_KEYS"),
+		},
+		&cli.StringFlag{
+			Name:    "jwt-secret",
+			Usage:   "Jwt HS256 secret, used only when no private key configured",
+			Sources: cli.EnvVars("JWT_SECRET"),
+		},
+		&cli.StringFlag{
+			Name:    "jwt-jwks-url",
+			Usage:   "Jwt verification keys published by the auth service, replaces the local keys on verify only services",
+			Sources: cli.EnvVars("JWT_JWKS_URL"),
+		},
+		&cli.DurationFlag{
+			Name:        "jwt-jwks-refresh-interval",
+			Usage:       "Minimal interval between the fetches of the published keys on unknown kid",
+			Value:       defJwtJwksRefreshInterval,
+			DefaultText: defJwtJwksRefreshInterval.String(),
+			Sources:     cli.EnvVars("JWT_JWKS_REFRESH_INTERVAL"),
+		},
+		&cli.StringFlag{
+			Name:        "jwt-issuer",
+			Usage:       "Jwt issuer (iss) stamped and expected",
+			Value:       defJwtIssuer,
+			DefaultText: defJwtIssuer,
+			Sources:     cli.EnvVars("JWT_ISSUER"),
+		},
+		&cli.StringFlag{
+			Name:        "jwt-audience",
+			Usage:       "Jwt audience (aud) stamped and expected",
+			Value:       defJwtAudience,
+			DefaultText: defJwtAudience,
+			Sources:     cli.EnvVars("JWT_AUDIENCE"),
+		},
+		&cli.StringSliceFlag{
+			Name:        "jwt-allowed-algorithms",
+			Usage:       "Jwt signing algorithms accepted on validation",
+			Value:       defJwtAllowedAlgorithms,
+			DefaultText: strings.Join(defJwtAllowedAlgorithms, ","),
+			Sources:     cli.EnvVars("JWT_ALLOWED_ALGORITHMS"),
+		},
+		&cli.DurationFlag{
+			Name:        "jwt-leeway",
+			Usage:       "Jwt clock skew leeway on time based claims",
+			Value:       defJwtLeeway,
+			DefaultText: defJwtLeeway.String(),
+			Sources:     cli.EnvVars("JWT_LEEWAY"),
+		},
+		&cli.DurationFlag{
+			Name:        "jwt-access-expiration",
+			Usage:       "Jwt access token expiration time",
+			Value:       defJwtAccessExpiration,
+			DefaultText: defJwtAccessExpiration.String(),
+			Sources:     cli.EnvVars("JWT_ACCESS_EXPIRATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "jwt-refresh-expiration",
+			Usage:       "Jwt refresh token expiration time",
+			Value:       defJwtRefreshExpiration,
+			DefaultText: defJwtRefreshExpiration.String(),
+			Sources:     cli.EnvVars("JWT_REFRESH_EXPIRATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "jwt-challenge-expiration",
+			Usage:       "Jwt 2fa sign-in challenge expiration time",
+			Value:       defJwtChallengeExpiration,
+			DefaultText: defJwtChallengeExpiration.String(),
+			Sources:     cli.EnvVars("JWT_CHALLENGE_EXPIRATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "jwt-verify-expiration",
+			Usage:       "Jwt email verification token expiration time",
+			Value:       defJwtVerifyExpiration,
+			DefaultText: defJwtVerifyExpiration.String(),
+			Sources:     cli.EnvVars("JWT_VERIFY_EXPIRATION"),
+		},
+		//
+		// Mailer section
+		//
+		&cli.StringFlag{
+			Name:        "mailer-transport",
+			Usage:       "Mailer transport (log, file, smtp)",
+			Value:       defMailerTransport,
+			DefaultText: defMailerTransport,
+			Sources:     cli.EnvVars("MAILER_TRANSPORT"),
+		},
+		&cli.StringFlag{
+			Name:        "mailer-from",
+			Usage:       "Mailer sender address",
+			Value:       defMailerFrom,
+			DefaultText: defMailerFrom,
+			Sources:     cli.EnvVars("MAILER_FROM"),
+		},
+		&cli.StringFlag{
+			Name:        "mailer-file-dir",
+			Usage:       "Mailer file transport directory",
+			Value:       defMailerFileDir,
+			DefaultText: defMailerFileDir,
+			Sources:     cli.EnvVars("MAILER_FILE_DIR"),
+		},
+		&cli.StringFlag{
+			Name:        "mailer-smtp-addr",
+			Usage:       "Mailer smtp transport server address",
+			Value:       defMailerSmtpAddr,
+			DefaultText: defMailerSmtpAddr,
+			Sources:     cli.EnvVars("MAILER_SMTP_ADDR"),
+		},
+		&cli.StringFlag{
+			Name:    "mailer-smtp-username",
+			Usage:   "Mailer smtp transport username",
+			Sources: cli.EnvVars("MAILER_SMTP_USERNAME"),
+		},
+		&cli.StringFlag{
+			Name:    "mailer-smtp-password",
+			Usage:   "Mailer smtp transport password",
+			Sources: cli.EnvVars("MAILER_SMTP_PASSWORD"),
+		},
+		//
+		// Auth section
+		//
+		&cli.StringFlag{
+			Name:        "auth-verify-url",
+			Usage:       "Email verification link, the token is appended as query parameter",
+			Value:       defAuthVerifyUrl,
+			DefaultText: defAuthVerifyUrl,
+			Sources:     cli.EnvVars("AUTH_VERIFY_URL"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-verify-resend-interval",
+			Usage:       "Minimal interval between verification emails to the same address",
+			Value:       defAuthVerifyResendInterval,
+			DefaultText: defAuthVerifyResendInterval.String(),
+			Sources:     cli.EnvVars("AUTH_VERIFY_RESEND_INTERVAL"),
+		},
+		&cli.StringFlag{
+			Name:        "auth-reset-url",
+			Usage:       "Password reset page link, the token is appended as query parameter",
+			Value:       defAuthResetUrl,
+			DefaultText: defAuthResetUrl,
+			Sources:     cli.EnvVars("AUTH_RESET_URL"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-reset-expiration",
+			Usage:       "Password reset token expiration time",
+			Value:       defAuthResetExpiration,
+			DefaultText: defAuthResetExpiration.String(),
+			Sources:     cli.EnvVars("AUTH_RESET_EXPIRATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-reset-interval",
+			Usage:       "Minimal interval between password reset emails to the same address",
+			Value:       defAuthResetInterval,
+			DefaultText: defAuthResetInterval.String(),
+			Sources:     cli.EnvVars("AUTH_RESET_INTERVAL"),
+		},
+		&cli.BoolFlag{
+			Name:        "auth-magic-link-enabled",
+			Usage:       "Passwordless sign-in by email link, users may still opt out",
+			Value:       defAuthMagicLinkEnabled,
+			DefaultText: strconv.FormatBool(defAuthMagicLinkEnabled),
+			Sources:     cli.EnvVars("AUTH_MAGIC_LINK_ENABLED"),
+		},
+		&cli.StringFlag{
+			Name:        "auth-magic-link-url",
+			Usage:       "Magic link sign-in page, the token is appended as query parameter",
+			Value:       defAuthMagicLinkUrl,
+			DefaultText: defAuthMagicLinkUrl,
+			Sources:     cli.EnvVars("AUTH_MAGIC_LINK_URL"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-magic-link-expiration",
+			Usage:       "Magic link token expiration time",
+			Value:       defAuthMagicLinkExpiration,
+			DefaultText: defAuthMagicLinkExpiration.String(),
+			Sources:     cli.EnvVars("AUTH_MAGIC_LINK_EXPIRATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-magic-link-interval",
+			Usage:       "Minimal interval between magic link emails to the same address",
+			Value:       defAuthMagicLinkInterval,
+			DefaultText: defAuthMagicLinkInterval.String(),
+			Sources:     cli.EnvVars("AUTH_MAGIC_LINK_INTERVAL"),
+		},
+		&cli.IntFlag{
+			Name:        "auth-lockout-threshold",
+			Usage:       "Failed sign-ins of a username before it is locked out, 0 disables",
+			Value:       int64(defAuthLockoutThreshold),
+			DefaultText: strconv.FormatInt(int64(defAuthLockoutThreshold), 10),
+			Sources:     cli.EnvVars("AUTH_LOCKOUT_THRESHOLD"),
+		},
+		&cli.IntFlag{
+			Name:        "auth-lockout-ip-threshold",
+			Usage:       "Failed sign-ins from a client address before it is locked out, 0 disables",
+			Value:       int64(defAuthLockoutIpThreshold),
+			DefaultText: strconv.FormatInt(int64(defAuthLockoutIpThreshold), 10),
+			Sources:     cli.EnvVars("AUTH_LOCKOUT_IP_THRESHOLD"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-lockout-duration",
+			Usage:       "First lockout, doubled by every further failed sign-in",
+			Value:       defAuthLockoutDuration,
+			DefaultText: defAuthLockoutDuration.String(),
+			Sources:     cli.EnvVars("AUTH_LOCKOUT_DURATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-lockout-max-duration",
+			Usage:       "Longest lockout",
+			Value:       defAuthLockoutMaxDuration,
+			DefaultText: defAuthLockoutMaxDuration.String(),
+			Sources:     cli.EnvVars("AUTH_LOCKOUT_MAX_DURATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-lockout-window",
+			Usage:       "How long the failed sign-ins are remembered since the last one",
+			Value:       defAuthLockoutWindow,
+			DefaultText: defAuthLockoutWindow.String(),
+			Sources:     cli.EnvVars("AUTH_LOCKOUT_WINDOW"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-principal-cache-ttl",
+			Usage:       "How long the user status and roles are cached by the auth middleware",
+			Value:       defAuthPrincipalCacheTTL,
+			DefaultText: defAuthPrincipalCacheTTL.String(),
+			Sources:     cli.EnvVars("AUTH_PRINCIPAL_CACHE_TTL"),
+		},
+		&cli.DurationFlag{
+			Name:        "auth-policy-cache-ttl",
+			Usage:       "How long the role permission policy is cached in process",
+			Value:       defAuthPolicyCacheTTL,
+			DefaultText: defAuthPolicyCacheTTL.String(),
+			Sources:     cli.EnvVars("AUTH_POLICY_CACHE_TTL"),
+		},
+		//
+		// Password policy section
+		//
+		&cli.IntFlag{
+			Name:        "password-min-length",
+			Usage:       "Password minimal length in characters",
+			Value:       int64(defPasswordMinLength),
+			DefaultText: strconv.FormatInt(int64(defPasswordMinLength), 10),
+			Sources:     cli.EnvVars("PASSWORD_MIN_LENGTH"),
+		},
+		&cli.StringSliceFlag{
+			Name:        "password-required-classes",
+			Usage:       "Character classes every password contains (lower, upper, digit, symbol)",
+			Value:       defPasswordRequiredClasses,
+			DefaultText: strings.Join(defPasswordRequiredClasses, ","),
+			Sources:     cli.EnvVars("PASSWORD_REQUIRED_CLASSES"),
+		},
+		&cli.StringFlag{
+			Name:    "password-breached-file",
+			Usage:   "Breached password list, one password or SHA-1 digest (HIBP format) per line",
+			Sources: cli.EnvVars("PASSWORD_BREACHED_FILE"),
+		},
+		&cli.StringFlag{
+			Name:        "password-hash-algorithm",
+			Usage:       "Algorithm of the new password hashes (argon2id, bcrypt), the weaker hashes are upgraded on sign-in",
+			Value:       defPasswordHashAlgorithm,
+			DefaultText: defPasswordHashAlgorithm,
+			Sources:     cli.EnvVars("PASSWORD_HASH_ALGORITHM"),
+		},
+		&cli.IntFlag{
+			Name:        "password-argon2-memory",
+			Usage:       "Argon2id memory in KiB",
+			Value:       int64(defPasswordArgon2Memory),
+			DefaultText: strconv.FormatInt(int64(defPasswordArgon2Memory), 10),
+			Sources:     cli.EnvVars("PASSWORD_ARGON2_MEMORY"),
+		},
+		&cli.IntFlag{
+			Name:        "password-argon2-iterations",
+			Usage:       "Argon2id iterations",
+			Value:       int64(defPasswordArgon2Iterations),
+			DefaultText: strconv.FormatInt(int64(defPasswordArgon2Iterations), 10),
+			Sources:     cli.EnvVars("PASSWORD_ARGON2_ITERATIONS"),
+		},
+		&cli.IntFlag{
+			Name:        "password-argon2-parallelism",
+			Usage:       "Argon2id parallelism (lanes)",
+			Value:       int64(defPasswordArgon2Parallelism),
+			DefaultText: strconv.FormatInt(int64(defPasswordArgon2Parallelism), 10),
+			Sources:     cli.EnvVars("PASSWORD_ARGON2_PARALLELISM"),
+		},
+		&cli.IntFlag{
+			Name:        "password-bcrypt-cost",
+			Usage:       "Bcrypt cost",
+			Value:       int64(defPasswordBcryptCost),
+			DefaultText: strconv.FormatInt(int64(defPasswordBcryptCost), 10),
+			Sources:     cli.EnvVars("PASSWORD_BCRYPT_COST"),
+		},
+		//
+		// OAuth section
+		//
+		&cli.DurationFlag{
+			Name:        "oauth-code-expiration",
+			Usage:       "Authorization code expiration",
+			Value:       defOAuthCodeExpiration,
+			DefaultText: defOAuthCodeExpiration.String(),
+			Sources:     cli.EnvVars("OAUTH_CODE_EXPIRATION"),
+		},
+		//
+		// OIDC federation section
+		//
+		&cli.StringFlag{
+			Name:    "oidc-config",
+			Usage:   "Identity providers config file (json), see internal/_req/oidc.json",
+			Sources: cli.EnvVars("OIDC_CONFIG"),
+		},
+		&cli.StringFlag{
+			Name:        "oidc-name",
+			Usage:       "Name of the identity provider configured by flags",
+			Value:       defOidcName,
+			DefaultText: defOidcName,
+			Sources:     cli.EnvVars("OIDC_NAME"),
+		},
+		&cli.StringFlag{
+			Name:    "oidc-issuer",
+			Usage:   "Issuer url of the identity provider configured by flags, empty disables it",
+			Sources: cli.EnvVars("OIDC_ISSUER"),
+		},
+		&cli.StringFlag{
+			Name:    "oidc-client-id",
+			Usage:   "Client id registered at the identity provider",
+			Sources: cli.EnvVars("OIDC_CLIENT_ID"),
+		},
+		&cli.StringFlag{
+			Name:    "oidc-client-secret",
+			Usage:   "Client secret registered at the identity provider",
+			Sources: cli.EnvVars("OIDC_CLIENT_SECRET"),
+		},
+		&cli.StringSliceFlag{
+			Name:        "oidc-scopes",
+			Usage:       "Scopes requested from identity providers without their own",
+			Value:       defOidcScopes,
+			DefaultText: strings.Join(defOidcScopes, ","),
+			Sources:     cli.EnvVars("OIDC_SCOPES"),
+		},
+		&cli.StringFlag{
+			Name:        "oidc-redirect-url",
+			Usage:       "Sign-in callback page, it posts the code and state back to the api",
+			Value:       defOidcRedirectUrl,
+			DefaultText: defOidcRedirectUrl,
+			Sources:     cli.EnvVars("OIDC_REDIRECT_URL"),
+		},
+		&cli.DurationFlag{
+			Name:        "oidc-state-expiration",
+			Usage:       "How long a federated sign-in may take",
+			Value:       defOidcStateExpiration,
+			DefaultText: defOidcStateExpiration.String(),
+			Sources:     cli.EnvVars("OIDC_STATE_EXPIRATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "oidc-timeout",
+			Usage:       "Identity provider request timeout",
+			Value:       defOidcTimeout,
+			DefaultText: defOidcTimeout.String(),
+			Sources:     cli.EnvVars("OIDC_TIMEOUT"),
+		},
+		//
+		// WebAuthn section
+		//
+		&cli.StringFlag{
+			Name:        "webauthn-rp-id",
+			Usage:       "WebAuthn relying party id, the domain the passkeys are scoped to",
+			Value:       defWebAuthnRPID,
+			DefaultText: defWebAuthnRPID,
+			Sources:     cli.EnvVars("WEBAUTHN_RP_ID"),
+		},
+		&cli.StringFlag{
+			Name:        "webauthn-rp-name",
+			Usage:       "WebAuthn relying party name shown by the authenticator",
+			Value:       defWebAuthnRPName,
+			DefaultText: defWebAuthnRPName,
+			Sources:     cli.EnvVars("WEBAUTHN_RP_NAME"),
+		},
+		&cli.StringSliceFlag{
+			Name:        "webauthn-rp-origins",
+			Usage:       "WebAuthn origins allowed to run the ceremonies",
+			Value:       defWebAuthnRPOrigins,
+			DefaultText: strings.Join(defWebAuthnRPOrigins, ","),
+			Sources:     cli.EnvVars("WEBAUTHN_RP_ORIGINS"),
+		},
+		&cli.StringFlag{
+			Name:        "webauthn-user-verification",
+			Usage:       "WebAuthn user verification of the second factor, passwordless sign-in always requires it",
+			Value:       defWebAuthnUV,
+			DefaultText: defWebAuthnUV,
+			Sources:     cli.EnvVars("WEBAUTHN_USER_VERIFICATION"),
+		},
+		&cli.DurationFlag{
+			Name:        "webauthn-state-expiration",
+			Usage:       "How long a WebAuthn ceremony may take",
+			Value:       defWebAuthnStateExpiry,
+			DefaultText: defWebAuthnStateExpiry.String(),
+			Sources:     cli.EnvVars("WEBAUTHN_STATE_EXPIRATION"),
+		},
+	}
+}
+
 // @title       Brickwall API
 // @version     0.1.0
 // @description This is Brickwall RestAPI
 // @host        localhost:8081
 // @BasePath    /api/v1
-func bootstrap(ctx context.Context) error {
+//
+// @securityDefinitions.apikey Bearer
+// @in                         header
+// @name                       Authorization
+// @description                Access token as "Bearer <token>", operations list the required permissions in x-permissions
+//
+// @securityDefinitions.apikey ApiKey
+// @in                         header
+// @name                       Authorization
+// @description                Service account key as "ApiKey <key>", the key scopes limit the permissions
+//
+// Serve wires the providers and the services, then serves the routes of the
+// service until it is interrupted
+func Serve(ctx context.Context, registerRoutes func(context.Context, provider.IRouterProvider)) error {
 	//
 	// Logger provider - no dependencies
 	//
@@ -347,6 +825,9 @@ func bootstrap(ctx context.Context) error {
 	// Jwt provider - depends on Redis
 	//
 	jwtProvider := provider.NewJwtProvider(ctx)
+	if err := jwtProvider.LoadKeys(); err != nil {
+		return err
+	}
 	ctx = context.WithValue(ctx, common.KeyJwtProvider, jwtProvider)
 	//
 	// twoFA provider - no dependencies
@@ -354,9 +835,52 @@ func bootstrap(ctx context.Context) error {
 	twoFAProvider := provider.New2FAProvider()
 	ctx = context.WithValue(ctx, common.Key2FAProvider, twoFAProvider)
 	//
+	// Mailer provider - no dependencies
+	//
+	mailerProvider, err := provider.NewMailerProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyMailerProvider, mailerProvider)
+	//
+	// Oidc provider - no dependencies, providers are discovered on first use
+	//
+	oidcProvider, err := provider.NewOidcProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyOidcProvider, oidcProvider)
+	//
+	// WebAuthn provider - no dependencies
+	//
+	webAuthnProvider, err := provider.NewWebAuthnProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyWebAuthnProvider, webAuthnProvider)
+	//
+	// Password policy provider - no dependencies, loads the breached password list
+	//
+	passwordPolicyProvider, err := provider.NewPasswordPolicyProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyPasswordPolicy, passwordPolicyProvider)
+	//
+	// Password hasher provider - no dependencies
+	//
+	passwordHasherProvider, err := provider.NewPasswordHasherProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyPasswordHasher, passwordHasherProvider)
+	//
 	// Router provider - no dependencies
 	//
-	routerProvider := provider.NewRouterProvider(ctx).Init()
+	routerProvider, err := provider.NewRouterProvider(ctx).Init()
+	if err != nil {
+		return err
+	}
 	ctx = context.WithValue(ctx, common.KeyRouterProvider, routerProvider)
 	//
 	// Service Manager - depends on pgx and sqlc storage.queries
@@ -369,7 +893,7 @@ func bootstrap(ctx context.Context) error {
 	validator := validator.New()
 	ctx = context.WithValue(ctx, common.KeyValidatorProvider, validator)
 
-	RegisterRoutes(ctx, routerProvider)
+	registerRoutes(ctx, routerProvider)
 	srv := provider.NewServerProvider(ctx).Startup(routerProvider)
 	defer srv.Shutdown()
 
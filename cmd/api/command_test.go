@@ -0,0 +1,18 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"brickwall/cmd/api"
+	"brickwall/internal/common"
+)
+
+func TestCommandProxyModeRequiresJwks(t *testing.T) {
+	command := api.Command(context.Background())
+	err := command.Run(context.Background(), []string{"api", "--auth-service-url", "http://auth:8082"})
+	if !errors.Is(err, common.ErrAuthServiceUrl) {
+		t.Fatalf("proxy mode without jwt-jwks-url started: %v", err)
+	}
+}
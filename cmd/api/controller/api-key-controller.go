@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
+)
+
+type IApiKeyController interface {
+	common.IController
+
+	// Service accounts
+	ServiceAccountNew(*gin.Context)
+	ServiceAccountSelect(*gin.Context)
+
+	// Api keys
+	ApiKeyNew(*gin.Context)
+	ApiKeySelect(*gin.Context)
+	ApiKeyRevoke(*gin.Context)
+}
+
+type ApiKeyController struct {
+	ctx           context.Context
+	group         *gin.RouterGroup
+	apiKeyService service.IApiKeyService
+}
+
+func NewApiKeyController(ctx context.Context, grp *gin.RouterGroup) IApiKeyController {
+	serviceManager := ctx.Value(common.KeyServiceManager).(service.IServiceManager)
+
+	return &ApiKeyController{
+		ctx: ctx, group: grp, apiKeyService: serviceManager.ApiKeyService(),
+	}
+}
+
+func (rcv *ApiKeyController) Register() {
+	read := middleware.RequirePermission(common.PermApiKeyRead)
+	write := middleware.RequirePermission(common.PermApiKeyWrite)
+
+	// Service accounts
+	rcv.group.POST("/service-account", write, rcv.ServiceAccountNew)
+	rcv.group.GET("/service-account", read, rcv.ServiceAccountSelect)
+
+	// Api keys
+	rcv.group.POST("/user/:id/api-key", write, rcv.ApiKeyNew)
+	rcv.group.GET("/user/:id/api-key", read, rcv.ApiKeySelect)
+	rcv.group.DELETE("/user/:id/api-key/:key", write, rcv.ApiKeyRevoke)
+}
+
+// @Summary       Create service account
+// @Description   Create a user that authenticates with api keys only
+// @Tags          api-key
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["apikey:write"]
+// @Param         request body exchange.ServiceAccountNewReq true "Service account"
+// @Success       201
+// @Router        /service-account [post]
+func (rcv *ApiKeyController) ServiceAccountNew(c *gin.Context) {
+	req := &exchange.ServiceAccountNewReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.apiKeyService.ServiceAccountNew(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewResponse(res))
+}
+
+// @Summary       List service accounts
+// @Description   Return all service accounts
+// @Tags          api-key
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["apikey:read"]
+// @Success       200
+// @Router        /service-account [get]
+func (rcv *ApiKeyController) ServiceAccountSelect(c *gin.Context) {
+	res, err := rcv.apiKeyService.ServiceAccountSelect()
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Issue api key
+// @Description   Issue a scoped api key for the service account, the key is returned only once
+// @Tags          api-key
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["apikey:write"]
+// @Param         id      path string                true "Service account id"
+// @Param         request body exchange.ApiKeyNewReq true "Api key"
+// @Success       201
+// @Router        /user/{id}/api-key [post]
+func (rcv *ApiKeyController) ApiKeyNew(c *gin.Context) {
+	uri := &exchange.UserUriID{}
+	req := &exchange.ApiKeyNewReq{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.apiKeyService.ApiKeyNew(principal, uri.ID, req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewResponse(res))
+}
+
+// @Summary       List api keys
+// @Description   Return the api keys of the service account without the secrets
+// @Tags          api-key
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["apikey:read"]
+// @Param         id path string true "Service account id"
+// @Success       200
+// @Router        /user/{id}/api-key [get]
+func (rcv *ApiKeyController) ApiKeySelect(c *gin.Context) {
+	uri := &exchange.UserUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.apiKeyService.ApiKeySelect(uri.ID)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Revoke api key
+// @Description   Revoke the api key, requests using it are rejected immediately
+// @Tags          api-key
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["apikey:write"]
+// @Param         id  path string true "Service account id"
+// @Param         key path string true "Api key id"
+// @Success       200
+// @Router        /user/{id}/api-key/{key} [delete]
+func (rcv *ApiKeyController) ApiKeyRevoke(c *gin.Context) {
+	uri := &exchange.ApiKeyUri{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.apiKeyService.ApiKeyRevoke(uri); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
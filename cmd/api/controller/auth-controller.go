@@ -8,8 +8,10 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
 	"brickwall/cmd/api/service"
 	"brickwall/internal/common"
+	"brickwall/internal/provider"
 )
 
 type IAuthController interface {
@@ -18,14 +20,50 @@ type IAuthController interface {
 	AuthSignup(*gin.Context)
 	AuthSignin(*gin.Context)
 	AuthSignout(*gin.Context)
+	AuthSignoutEverywhere(*gin.Context)
 
 	AuthRefreshToken(*gin.Context)
 	AuthInvalidateToken(*gin.Context)
 
+	AuthVerifyLink(*gin.Context)
+	AuthVerify(*gin.Context)
+	AuthVerifyResend(*gin.Context)
+
 	AuthResetPassword(*gin.Context)
+	AuthResetPasswordConfirm(*gin.Context)
 	AuthChangePassword(*gin.Context)
 
+	AuthMagicLink(*gin.Context)
+	AuthMagicLinkConsume(*gin.Context)
+	AuthMagicLinkToggle(*gin.Context)
+
 	AuthMe(*gin.Context)
+
+	Auth2FAEnroll(*gin.Context)
+	Auth2FAConfirm(*gin.Context)
+	Auth2FADisable(*gin.Context)
+	Auth2FAVerify(*gin.Context)
+	Auth2FARecoveryCodes(*gin.Context)
+
+	AuthOidcProviders(*gin.Context)
+	AuthOidcStart(*gin.Context)
+	AuthOidcCallback(*gin.Context)
+	AuthIdentitySelect(*gin.Context)
+	AuthIdentityLink(*gin.Context)
+	AuthIdentityLinkCallback(*gin.Context)
+	AuthIdentityUnlink(*gin.Context)
+
+	AuthWebAuthnRegisterBegin(*gin.Context)
+	AuthWebAuthnRegisterFinish(*gin.Context)
+	AuthWebAuthnLoginBegin(*gin.Context)
+	AuthWebAuthnLoginFinish(*gin.Context)
+	AuthWebAuthn2FABegin(*gin.Context)
+	AuthWebAuthn2FAFinish(*gin.Context)
+	AuthWebAuthnCredentialSelect(*gin.Context)
+	AuthWebAuthnCredentialDelete(*gin.Context)
+
+	AuthSessionSelect(*gin.Context)
+	AuthSessionRevoke(*gin.Context)
 }
 
 type AuthController struct {
@@ -33,6 +71,7 @@ type AuthController struct {
 	group       *gin.RouterGroup
 	authService service.IAuthService
 	userService service.IUserService
+	auth        gin.HandlerFunc
 }
 
 func NewAuthController(ctx context.Context, grp *gin.RouterGroup) IAuthController {
@@ -43,13 +82,56 @@ func NewAuthController(ctx context.Context, grp *gin.RouterGroup) IAuthControlle
 		group:       grp,
 		authService: serviceManager.AuthService(),
 		userService: serviceManager.UserService(),
+		auth:        middleware.SessionMiddleware(ctx),
 	}
 }
 
 func (rcv *AuthController) Register() {
 	rcv.group.POST("/auth/signup", rcv.AuthSignup)
 	rcv.group.POST("/auth/signin", rcv.AuthSignin)
-	rcv.group.POST("/auth/signout", rcv.AuthSignout)
+	rcv.group.POST("/auth/signout", rcv.auth, rcv.AuthSignout)
+	rcv.group.POST("/auth/signout/everywhere", rcv.auth, rcv.AuthSignoutEverywhere)
+	rcv.group.POST("/auth/refresh", rcv.AuthRefreshToken)
+	rcv.group.GET("/auth/me", rcv.auth, rcv.AuthMe)
+
+	rcv.group.GET("/auth/verify", rcv.AuthVerifyLink)
+	rcv.group.POST("/auth/verify", rcv.AuthVerify)
+	rcv.group.POST("/auth/verify/resend", rcv.AuthVerifyResend)
+
+	rcv.group.POST("/auth/password", rcv.auth, rcv.AuthChangePassword)
+	rcv.group.POST("/auth/password/reset", rcv.AuthResetPassword)
+	rcv.group.POST("/auth/password/reset/confirm", rcv.AuthResetPasswordConfirm)
+
+	rcv.group.POST("/auth/magic-link", rcv.AuthMagicLink)
+	rcv.group.POST("/auth/magic-link/consume", rcv.AuthMagicLinkConsume)
+	rcv.group.PUT("/auth/magic-link", rcv.auth, rcv.AuthMagicLinkToggle)
+
+	rcv.group.POST("/auth/2fa/enroll", rcv.auth, rcv.Auth2FAEnroll)
+	rcv.group.POST("/auth/2fa/confirm", rcv.auth, rcv.Auth2FAConfirm)
+	rcv.group.POST("/auth/2fa/disable", rcv.auth, rcv.Auth2FADisable)
+	rcv.group.POST("/auth/2fa/recovery-codes", rcv.auth, rcv.Auth2FARecoveryCodes)
+	rcv.group.POST("/auth/2fa/verify", rcv.Auth2FAVerify)
+
+	rcv.group.GET("/auth/oidc", rcv.AuthOidcProviders)
+	rcv.group.GET("/auth/oidc/:provider", rcv.AuthOidcStart)
+	rcv.group.POST("/auth/oidc/:provider/callback", rcv.AuthOidcCallback)
+
+	rcv.group.GET("/auth/identity", rcv.auth, rcv.AuthIdentitySelect)
+	rcv.group.POST("/auth/identity/:provider", rcv.auth, rcv.AuthIdentityLink)
+	rcv.group.POST("/auth/identity/:provider/callback", rcv.auth, rcv.AuthIdentityLinkCallback)
+	rcv.group.DELETE("/auth/identity/:id", rcv.auth, rcv.AuthIdentityUnlink)
+
+	rcv.group.POST("/auth/webauthn/register/begin", rcv.auth, rcv.AuthWebAuthnRegisterBegin)
+	rcv.group.POST("/auth/webauthn/register/finish", rcv.auth, rcv.AuthWebAuthnRegisterFinish)
+	rcv.group.POST("/auth/webauthn/login/begin", rcv.AuthWebAuthnLoginBegin)
+	rcv.group.POST("/auth/webauthn/login/finish", rcv.AuthWebAuthnLoginFinish)
+	rcv.group.POST("/auth/webauthn/2fa/begin", rcv.AuthWebAuthn2FABegin)
+	rcv.group.POST("/auth/webauthn/2fa/finish", rcv.AuthWebAuthn2FAFinish)
+	rcv.group.GET("/auth/webauthn/credential", rcv.auth, rcv.AuthWebAuthnCredentialSelect)
+	rcv.group.DELETE("/auth/webauthn/credential/:id", rcv.auth, rcv.AuthWebAuthnCredentialDelete)
+
+	rcv.group.GET("/auth/sessions", rcv.auth, rcv.AuthSessionSelect)
+	rcv.group.DELETE("/auth/sessions/:id", rcv.auth, rcv.AuthSessionRevoke)
 }
 
 func (rcv *AuthController) AuthSignup(c *gin.Context) {
@@ -74,8 +156,11 @@ func (rcv *AuthController) AuthSignin(c *gin.Context) {
 		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
 		return
 	}
-	res, err := rcv.authService.Signin(qry)
+	res, err := rcv.authService.Signin(qry, authDevice(c))
 	if err != nil {
+		if retryAfter, ok := common.RetryAfter(err); ok {
+			c.Header("Retry-After", retryAfter)
+		}
 		c.JSON(common.ErrMapper(err))
 		return
 	}
@@ -83,25 +168,491 @@ func (rcv *AuthController) AuthSignin(c *gin.Context) {
 }
 
 func (rcv *AuthController) AuthSignout(c *gin.Context) {
-	ok, err := rcv.authService.Signout()
+	req := &exchange.AuthSignoutReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.Signout(c.GetString(middleware.KeyToken), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthSignoutEverywhere(c *gin.Context) {
+	res, err := rcv.authService.SignoutEverywhere(c.GetString(middleware.KeyToken))
 	if err != nil {
 		c.JSON(common.ErrMapper(err))
 		return
 	}
-	c.JSON(http.StatusOK, common.NewResponse(ok))
+	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
-func (rcv *AuthController) AuthRefreshToken(*gin.Context) {
+func (rcv *AuthController) AuthRefreshToken(c *gin.Context) {
+	req := &exchange.AuthTokenRefreshReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.Refresh(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
 func (rcv *AuthController) AuthInvalidateToken(*gin.Context) {
 }
 
-func (rcv *AuthController) AuthResetPassword(*gin.Context) {
+// AuthVerifyLink serves the link from the verification email
+func (rcv *AuthController) AuthVerifyLink(c *gin.Context) {
+	qry := &exchange.AuthVerifyReq{}
+
+	if err := c.ShouldBindQuery(&qry); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.Verify(qry)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthVerify(c *gin.Context) {
+	req := &exchange.AuthVerifyReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.Verify(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthVerifyResend(c *gin.Context) {
+	req := &exchange.AuthVerifyResendReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.VerifyResend(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthResetPassword(c *gin.Context) {
+	req := &exchange.AuthPasswordResetReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.PasswordReset(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthResetPasswordConfirm(c *gin.Context) {
+	req := &exchange.AuthPasswordResetConfirmReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.PasswordResetConfirm(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthMagicLink(c *gin.Context) {
+	req := &exchange.AuthMagicLinkReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.MagicLink(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthMagicLinkConsume(c *gin.Context) {
+	req := &exchange.AuthMagicLinkConsumeReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.MagicLinkConsume(req, authDevice(c))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthMagicLinkToggle(c *gin.Context) {
+	req := &exchange.AuthMagicLinkToggleReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.MagicLinkToggle(c.GetString(middleware.KeyUserID), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthChangePassword(c *gin.Context) {
+	req := &exchange.AuthPasswordChangeReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.PasswordChange(c.MustGet(middleware.KeyClaims).(*provider.Claims), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthMe(c *gin.Context) {
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.authService.Me(principal)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) Auth2FAEnroll(c *gin.Context) {
+	res, err := rcv.authService.TwoFAEnroll(c.GetString(middleware.KeyUserID))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) Auth2FAConfirm(c *gin.Context) {
+	req := &exchange.Auth2FACodeReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.TwoFAConfirm(c.GetString(middleware.KeyUserID), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) Auth2FADisable(c *gin.Context) {
+	req := &exchange.Auth2FACodeReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.TwoFADisable(c.GetString(middleware.KeyUserID), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) Auth2FAVerify(c *gin.Context) {
+	req := &exchange.Auth2FAVerifyReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.TwoFAVerify(req, authDevice(c))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) Auth2FARecoveryCodes(c *gin.Context) {
+	req := &exchange.Auth2FACodeReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.TwoFARecoveryCodes(c.GetString(middleware.KeyUserID), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthOidcProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, common.NewResponse(rcv.authService.OidcProviders()))
+}
+
+func (rcv *AuthController) AuthOidcStart(c *gin.Context) {
+	uri := &exchange.AuthOidcUri{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.OidcStart(uri.Provider, "")
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
-func (rcv *AuthController) AuthChangePassword(*gin.Context) {
+func (rcv *AuthController) AuthOidcCallback(c *gin.Context) {
+	uri := &exchange.AuthOidcUri{}
+	req := &exchange.AuthOidcCallbackReq{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.OidcSignin(uri.Provider, req, authDevice(c))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthIdentitySelect(c *gin.Context) {
+	res, err := rcv.authService.IdentitySelect(c.GetString(middleware.KeyUserID))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthIdentityLink(c *gin.Context) {
+	uri := &exchange.AuthOidcUri{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.OidcStart(uri.Provider, c.GetString(middleware.KeyUserID))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthIdentityLinkCallback(c *gin.Context) {
+	uri := &exchange.AuthOidcUri{}
+	req := &exchange.AuthOidcCallbackReq{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.OidcLink(uri.Provider, c.GetString(middleware.KeyUserID), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthIdentityUnlink(c *gin.Context) {
+	uri := &exchange.AuthIdentityUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.authService.IdentityUnlink(c.GetString(middleware.KeyUserID), uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
+
+func (rcv *AuthController) AuthWebAuthnRegisterBegin(c *gin.Context) {
+	res, err := rcv.authService.WebAuthnRegisterBegin(c.GetString(middleware.KeyUserID))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthnRegisterFinish(c *gin.Context) {
+	req := &exchange.AuthWebAuthnRegisterFinishReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.WebAuthnRegisterFinish(c.GetString(middleware.KeyUserID), req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthnLoginBegin(c *gin.Context) {
+	res, err := rcv.authService.WebAuthnLoginBegin()
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthnLoginFinish(c *gin.Context) {
+	req := &exchange.AuthWebAuthnFinishReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.WebAuthnLoginFinish(req, authDevice(c))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthn2FABegin(c *gin.Context) {
+	req := &exchange.AuthWebAuthn2FABeginReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.WebAuthn2FABegin(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthn2FAFinish(c *gin.Context) {
+	req := &exchange.AuthWebAuthn2FAFinishReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.WebAuthn2FAFinish(req, authDevice(c))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthnCredentialSelect(c *gin.Context) {
+	res, err := rcv.authService.WebAuthnCredentialSelect(c.GetString(middleware.KeyUserID))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthWebAuthnCredentialDelete(c *gin.Context) {
+	uri := &exchange.AuthWebAuthnUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.authService.WebAuthnCredentialDelete(c.GetString(middleware.KeyUserID), uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
+
+func (rcv *AuthController) AuthSessionSelect(c *gin.Context) {
+	res, err := rcv.authService.SessionSelect(c.GetString(middleware.KeyUserID))
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+func (rcv *AuthController) AuthSessionRevoke(c *gin.Context) {
+	uri := &exchange.AuthSessionUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.authService.SessionRevoke(c.GetString(middleware.KeyUserID), uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
 }
 
-func (rcv *AuthController) AuthMe(*gin.Context) {
+// authDevice describes the client signing in for the session record
+func authDevice(c *gin.Context) *exchange.AuthDevice {
+	return &exchange.AuthDevice{UserAgent: c.Request.UserAgent(), IpAddress: c.ClientIP()}
 }
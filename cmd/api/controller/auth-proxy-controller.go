@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/urfave/cli/v3"
+
+	"brickwall/internal/common"
+)
+
+const keyClientIP common.KeyString = "key-client-ip"
+
+type IAuthProxyController interface {
+	common.IController
+
+	Proxy(*gin.Context)
+}
+
+// AuthProxyController forwards the auth routes to the standalone auth service,
+// the clients keep talking to a single api address
+type AuthProxyController struct {
+	ctx    context.Context
+	engine *gin.Engine
+	proxy  *httputil.ReverseProxy
+}
+
+func NewAuthProxyController(ctx context.Context, engine *gin.Engine) IAuthProxyController {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	// the url is validated when the command starts
+	target, _ := url.ParseRequestURI(cli.String("auth-service-url"))
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.SetXForwarded()
+			// the client address resolved by the trusted proxies, not the one the client claims
+			if clientIP, ok := r.In.Context().Value(keyClientIP).(string); ok {
+				r.Out.Header.Set("X-Forwarded-For", clientIP)
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.Error("auth service unreachable", "path", r.URL.Path, "error", err.Error())
+			status, body := common.ErrMapper(fmt.Errorf("%w: %v", common.ErrAuthUnavailable, err))
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(body)
+		},
+	}
+	return &AuthProxyController{
+		ctx: ctx, engine: engine, proxy: proxy,
+	}
+}
+
+func (rcv *AuthProxyController) Register() {
+	rcv.engine.Any("/.well-known/*path", rcv.Proxy)
+	rcv.engine.Any("/api/v1/auth/*path", rcv.Proxy)
+	rcv.engine.Any("/api/v1/oauth/*path", rcv.Proxy)
+}
+
+// Proxy forwards the request as is, cookies and the client address included
+func (rcv *AuthProxyController) Proxy(c *gin.Context) {
+	ctx := context.WithValue(c.Request.Context(), keyClientIP, c.ClientIP())
+	rcv.proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
 	"brickwall/cmd/api/service"
 	"brickwall/internal/common"
 )
@@ -40,17 +41,32 @@ func NewCountryController(ctx context.Context, grp *gin.RouterGroup) ICountryCon
 }
 
 func (rcv *CountryController) Register() {
+	// only ADMIN mutates the dictionaries, and only with the permission granted to the role
+	admin := middleware.RequireRoles(common.RoleAdmin)
+	write := middleware.RequirePermission(common.PermCountryWrite)
+
 	// CRUD operations
-	rcv.group.POST("/country", rcv.CountryNew)
+	rcv.group.POST("/country", admin, write, rcv.CountryNew)
 	rcv.group.GET("/country", rcv.CountrySelect)
 	rcv.group.GET("/country/:id", rcv.CountrySelectByID)
-	rcv.group.PUT("/country", rcv.CountryUpdateByID)
-	rcv.group.DELETE("/country/:id", rcv.CountryDeleteByID)
+	rcv.group.PUT("/country", admin, write, rcv.CountryUpdateByID)
+	rcv.group.DELETE("/country/:id", admin, write, rcv.CountryDeleteByID)
 
 	// Business logic operations
 	rcv.group.GET("/country-currency", rcv.CountryCurrencySelect)
 }
 
+// @Summary       Create country
+// @Description   Add the country to the dictionary
+// @Tags          country
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["country:write"]
+// @Param         request body exchange.CountryNewReq true "Country"
+// @Success       201
+// @Router        /country [post]
 func (rcv *CountryController) CountryNew(c *gin.Context) {
 	req := &exchange.CountryNewReq{}
 
@@ -98,6 +114,17 @@ func (rcv *CountryController) CountrySelectByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Update country
+// @Description   Change the country by id
+// @Tags          country
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["country:write"]
+// @Param         request body exchange.CountryUpdateReq true "Country"
+// @Success       200
+// @Router        /country [put]
 func (rcv *CountryController) CountryUpdateByID(c *gin.Context) {
 	req := &exchange.CountryUpdateReq{}
 
@@ -113,6 +140,17 @@ func (rcv *CountryController) CountryUpdateByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Delete country
+// @Description   Remove the country from the dictionary
+// @Tags          country
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["country:write"]
+// @Param         id path string true "Country id"
+// @Success       200
+// @Router        /country/{id} [delete]
 func (rcv *CountryController) CountryDeleteByID(c *gin.Context) {
 	uri := &exchange.CountryUriID{}
 
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
 	"brickwall/cmd/api/service"
 	"brickwall/internal/common"
 )
@@ -40,17 +41,32 @@ func NewCurrencyController(ctx context.Context, grp *gin.RouterGroup) ICurrencyC
 }
 
 func (rcv *CurrencyController) Register() {
+	// only ADMIN mutates the dictionaries, and only with the permission granted to the role
+	admin := middleware.RequireRoles(common.RoleAdmin)
+	write := middleware.RequirePermission(common.PermCurrencyWrite)
+
 	// CRUD operations
-	rcv.group.POST("/currency", rcv.CurrencyNew)
+	rcv.group.POST("/currency", admin, write, rcv.CurrencyNew)
 	rcv.group.GET("/currency", rcv.CurrencySelect)
 	rcv.group.GET("/currency/:id", rcv.CurrencySelectByID)
-	rcv.group.PUT("/currency", rcv.CurrencyUpdateByID)
-	rcv.group.DELETE("/currency/:id", rcv.CurrencyDeleteByID)
+	rcv.group.PUT("/currency", admin, write, rcv.CurrencyUpdateByID)
+	rcv.group.DELETE("/currency/:id", admin, write, rcv.CurrencyDeleteByID)
 
 	// Business logic operations
 	rcv.group.GET("/currency-country", rcv.CurrencyCountrySelect)
 }
 
+// @Summary       Create currency
+// @Description   Add the currency to the dictionary
+// @Tags          currency
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["currency:write"]
+// @Param         request body exchange.CurrencyNewReq true "Currency"
+// @Success       201
+// @Router        /currency [post]
 func (rcv *CurrencyController) CurrencyNew(c *gin.Context) {
 	req := &exchange.CurrencyNewReq{}
 
@@ -98,6 +114,17 @@ func (rcv *CurrencyController) CurrencySelectByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Update currency
+// @Description   Change the currency by id
+// @Tags          currency
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["currency:write"]
+// @Param         request body exchange.CurrencyUpdateReq true "Currency"
+// @Success       200
+// @Router        /currency [put]
 func (rcv *CurrencyController) CurrencyUpdateByID(c *gin.Context) {
 	req := &exchange.CurrencyUpdateReq{}
 
@@ -113,6 +140,17 @@ func (rcv *CurrencyController) CurrencyUpdateByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Delete currency
+// @Description   Remove the currency from the dictionary
+// @Tags          currency
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["currency:write"]
+// @Param         id path string true "Currency id"
+// @Success       200
+// @Router        /currency/{id} [delete]
 func (rcv *CurrencyController) CurrencyDeleteByID(c *gin.Context) {
 	uri := &exchange.CurrencyUriID{}
 
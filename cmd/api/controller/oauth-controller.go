@@ -0,0 +1,324 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
+)
+
+type IOAuthController interface {
+	common.IController
+
+	// Client registration
+	OAuthClientNew(*gin.Context)
+	OAuthClientSelect(*gin.Context)
+	OAuthClientDeleteByID(*gin.Context)
+
+	// Authorization server
+	OAuthAuthorize(*gin.Context)
+	OAuthAuthorizeConsent(*gin.Context)
+	OAuthToken(*gin.Context)
+	OAuthIntrospect(*gin.Context)
+
+	// Consents
+	OAuthConsentSelect(*gin.Context)
+	OAuthConsentRevoke(*gin.Context)
+}
+
+type OAuthController struct {
+	ctx          context.Context
+	group        *gin.RouterGroup
+	oauthService service.IOAuthService
+	auth         gin.HandlerFunc
+	session      gin.HandlerFunc
+}
+
+func NewOAuthController(ctx context.Context, grp *gin.RouterGroup) IOAuthController {
+	serviceManager := ctx.Value(common.KeyServiceManager).(service.IServiceManager)
+
+	return &OAuthController{
+		ctx:          ctx,
+		group:        grp,
+		oauthService: serviceManager.OAuthService(),
+		auth:         middleware.AuthMiddleware(ctx),
+		session:      middleware.SessionMiddleware(ctx),
+	}
+}
+
+func (rcv *OAuthController) Register() {
+	read := middleware.RequirePermission(common.PermOAuthRead)
+	write := middleware.RequirePermission(common.PermOAuthWrite)
+
+	// Client registration
+	rcv.group.POST("/oauth/client", rcv.auth, write, rcv.OAuthClientNew)
+	rcv.group.GET("/oauth/client", rcv.auth, read, rcv.OAuthClientSelect)
+	rcv.group.DELETE("/oauth/client/:id", rcv.auth, write, rcv.OAuthClientDeleteByID)
+
+	// Authorization server, the token endpoint authenticates the client itself
+	rcv.group.GET("/oauth/authorize", rcv.session, rcv.OAuthAuthorize)
+	rcv.group.POST("/oauth/authorize", rcv.session, rcv.OAuthAuthorizeConsent)
+	rcv.group.POST("/oauth/token", rcv.OAuthToken)
+
+	// Token introspection for the other services, authenticated by their service account
+	rcv.group.POST("/oauth/introspect", rcv.auth, middleware.RequirePermission(common.PermTokenIntrospect), rcv.OAuthIntrospect)
+
+	// Consents of the signed-in user
+	rcv.group.GET("/oauth/consent", rcv.session, rcv.OAuthConsentSelect)
+	rcv.group.DELETE("/oauth/consent/:id", rcv.session, rcv.OAuthConsentRevoke)
+}
+
+// @Summary       Register OAuth client
+// @Description   Register the client, the secret of a confidential client is returned only once
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["oauth:write"]
+// @Param         request body exchange.OAuthClientNewReq true "Client"
+// @Success       201
+// @Router        /oauth/client [post]
+func (rcv *OAuthController) OAuthClientNew(c *gin.Context) {
+	req := &exchange.OAuthClientNewReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.oauthService.OAuthClientNew(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewResponse(res))
+}
+
+// @Summary       List OAuth clients
+// @Description   Return the registered clients without the secrets
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["oauth:read"]
+// @Success       200
+// @Router        /oauth/client [get]
+func (rcv *OAuthController) OAuthClientSelect(c *gin.Context) {
+	res, err := rcv.oauthService.OAuthClientSelect()
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Remove OAuth client
+// @Description   Remove the client and the consents given to it
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["oauth:write"]
+// @Param         id path string true "Client id"
+// @Success       200
+// @Router        /oauth/client/{id} [delete]
+func (rcv *OAuthController) OAuthClientDeleteByID(c *gin.Context) {
+	uri := &exchange.OAuthClientUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.oauthService.OAuthClientDeleteByID(uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
+
+// @Summary       Authorize OAuth client
+// @Description   Validate the authorization request, returns the redirect with the code when the user already consented
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Param         response_type         query string true  "Must be code"
+// @Param         client_id             query string true  "Client id"
+// @Param         redirect_uri          query string true  "Registered redirect uri"
+// @Param         scope                 query string false "Space separated scopes"
+// @Param         state                 query string false "Opaque client state"
+// @Param         code_challenge        query string true  "PKCE code challenge"
+// @Param         code_challenge_method query string true  "Must be S256"
+// @Success       200
+// @Router        /oauth/authorize [get]
+func (rcv *OAuthController) OAuthAuthorize(c *gin.Context) {
+	req := &exchange.OAuthAuthorizeReq{}
+
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.oauthService.Authorize(principal, req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Consent to OAuth client
+// @Description   Approve or deny the authorization request, returns the redirect back to the client
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Param         request body exchange.OAuthAuthorizeConsentReq true "Authorization request and decision"
+// @Success       200
+// @Router        /oauth/authorize [post]
+func (rcv *OAuthController) OAuthAuthorizeConsent(c *gin.Context) {
+	req := &exchange.OAuthAuthorizeConsentReq{}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.oauthService.AuthorizeConsent(principal, req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Issue OAuth tokens
+// @Description   RFC 6749 token endpoint for the authorization_code, refresh_token and client_credentials grants, confidential clients authenticate with HTTP basic or the form
+// @Tags          oauth
+// @Accept        x-www-form-urlencoded
+// @Produce       json
+// @Param         grant_type    formData string true  "Grant type"
+// @Param         code          formData string false "Authorization code"
+// @Param         redirect_uri  formData string false "Redirect uri of the authorization request"
+// @Param         code_verifier formData string false "PKCE code verifier"
+// @Param         refresh_token formData string false "Refresh token"
+// @Param         scope         formData string false "Space separated scopes"
+// @Param         client_id     formData string false "Client id"
+// @Param         client_secret formData string false "Client secret"
+// @Success       200 {object} exchange.OAuthTokenRes
+// @Failure       400 {object} common.OAuthException
+// @Failure       401 {object} common.OAuthException
+// @Router        /oauth/token [post]
+func (rcv *OAuthController) OAuthToken(c *gin.Context) {
+	req := &exchange.OAuthTokenReq{}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(common.OAuthErrMapper(fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, err)))
+		return
+	}
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = clientID, clientSecret
+	}
+	res, err := rcv.oauthService.Token(req)
+	if err != nil {
+		code, exception := common.OAuthErrMapper(err)
+		if code == http.StatusUnauthorized {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		c.JSON(code, exception)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary       Introspect token
+// @Description   RFC 7662 introspection of the access and refresh tokens, revoked, expired and rotated tokens and tokens of blocked users are inactive
+// @Tags          oauth
+// @Accept        x-www-form-urlencoded
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["token:introspect"]
+// @Param         token           formData string true  "Token to introspect"
+// @Param         token_type_hint formData string false "access_token or refresh_token"
+// @Success       200 {object} exchange.OAuthIntrospectRes
+// @Failure       400 {object} common.OAuthException
+// @Router        /oauth/introspect [post]
+func (rcv *OAuthController) OAuthIntrospect(c *gin.Context) {
+	req := &exchange.OAuthIntrospectReq{}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(common.OAuthErrMapper(fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, err)))
+		return
+	}
+	res, err := rcv.oauthService.Introspect(req)
+	if err != nil {
+		c.JSON(common.OAuthErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary       List OAuth consents
+// @Description   Return the clients the signed-in user consented to
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Success       200
+// @Router        /oauth/consent [get]
+func (rcv *OAuthController) OAuthConsentSelect(c *gin.Context) {
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.oauthService.ConsentSelect(principal.UserID)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Revoke OAuth consent
+// @Description   Withdraw the consent given to the client, its refresh tokens stop working
+// @Tags          oauth
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Param         id path string true "Client id"
+// @Success       200
+// @Router        /oauth/consent/{id} [delete]
+func (rcv *OAuthController) OAuthConsentRevoke(c *gin.Context) {
+	uri := &exchange.OAuthClientUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	if err := rcv.oauthService.ConsentRevoke(principal.UserID, uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
@@ -0,0 +1,281 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
+)
+
+type IPermissionController interface {
+	common.IController
+
+	// CRUD operations
+	PermissionNew(*gin.Context)
+	PermissionSelect(*gin.Context)
+	PermissionSelectByID(*gin.Context)
+	PermissionUpdateByID(*gin.Context)
+	PermissionDeleteByID(*gin.Context)
+
+	// Business operations
+	RolePermissionSelect(*gin.Context)
+	RolePermissionGrant(*gin.Context)
+	RolePermissionRevoke(*gin.Context)
+}
+
+type PermissionController struct {
+	ctx               context.Context
+	group             *gin.RouterGroup
+	permissionService service.IPermissionService
+}
+
+func NewPermissionController(ctx context.Context, grp *gin.RouterGroup) IPermissionController {
+	serviceManager := ctx.Value(common.KeyServiceManager).(service.IServiceManager)
+
+	return &PermissionController{
+		ctx: ctx, group: grp, permissionService: serviceManager.PermissionService(),
+	}
+}
+
+func (rcv *PermissionController) Register() {
+	read := middleware.RequirePermission(common.PermPermissionRead)
+	write := middleware.RequirePermission(common.PermPermissionWrite)
+
+	// CRUD operations
+	rcv.group.POST("/permission", write, rcv.PermissionNew)
+	rcv.group.GET("/permission", read, rcv.PermissionSelect)
+	rcv.group.GET("/permission/:id", read, rcv.PermissionSelectByID)
+	rcv.group.PUT("/permission", write, rcv.PermissionUpdateByID)
+	rcv.group.DELETE("/permission/:id", write, rcv.PermissionDeleteByID)
+
+	// Business operations
+	rcv.group.GET("/role/:id/permission", read, rcv.RolePermissionSelect)
+	rcv.group.POST("/role/:id/permission", write, rcv.RolePermissionGrant)
+	rcv.group.DELETE("/role/:id/permission/:permission", write, rcv.RolePermissionRevoke)
+}
+
+// @Summary       Create permission
+// @Description   Add the permission to the catalog
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:write"]
+// @Param         request body exchange.PermissionNewReq true "Permission"
+// @Success       201
+// @Router        /permission [post]
+func (rcv *PermissionController) PermissionNew(c *gin.Context) {
+	req := &exchange.PermissionNewReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.permissionService.PermissionNew(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewResponse(res))
+}
+
+// @Summary       List permissions
+// @Description   Return the permission catalog page
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:read"]
+// @Param         page  query int    true  "Page"
+// @Param         size  query int    true  "Page size"
+// @Param         order query string false "Order"
+// @Success       200
+// @Router        /permission [get]
+func (rcv *PermissionController) PermissionSelect(c *gin.Context) {
+	qry := &exchange.PermissionQuery{}
+
+	if err := c.ShouldBindQuery(&qry); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, pag, err := rcv.permissionService.PermissionSelect(c, qry)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"data": res, "paginator": pag}),
+	)
+}
+
+// @Summary       Get permission
+// @Description   Return the permission by id
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:read"]
+// @Param         id path string true "Permission id"
+// @Success       200
+// @Router        /permission/{id} [get]
+func (rcv *PermissionController) PermissionSelectByID(c *gin.Context) {
+	uri := &exchange.PermissionUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.permissionService.PermissionSelectByID(uri.ID)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Update permission
+// @Description   Change the permission code and description
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:write"]
+// @Param         request body exchange.PermissionUpdateReq true "Permission"
+// @Success       200
+// @Router        /permission [put]
+func (rcv *PermissionController) PermissionUpdateByID(c *gin.Context) {
+	req := &exchange.PermissionUpdateReq{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.permissionService.PermissionUpdateByID(req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Delete permission
+// @Description   Remove the permission from the catalog and from every role
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:write"]
+// @Param         id path string true "Permission id"
+// @Success       200
+// @Router        /permission/{id} [delete]
+func (rcv *PermissionController) PermissionDeleteByID(c *gin.Context) {
+	uri := &exchange.PermissionUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.permissionService.PermissionDeleteByID(uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
+
+// @Summary       List role permissions
+// @Description   Return the permissions granted to the role
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:read"]
+// @Param         id path string true "Role id"
+// @Success       200
+// @Router        /role/{id}/permission [get]
+func (rcv *PermissionController) RolePermissionSelect(c *gin.Context) {
+	uri := &exchange.RoleUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.permissionService.RolePermissionSelect(uri.ID)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Grant role permission
+// @Description   Grant the permission to the role
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:write"]
+// @Param         id      path string                          true "Role id"
+// @Param         request body exchange.RolePermissionGrantReq true "Permission"
+// @Success       200
+// @Router        /role/{id}/permission [post]
+func (rcv *PermissionController) RolePermissionGrant(c *gin.Context) {
+	uri := &exchange.RoleUriID{}
+	req := &exchange.RolePermissionGrantReq{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.permissionService.RolePermissionGrant(uri.ID, req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Revoke role permission
+// @Description   Revoke the permission from the role
+// @Tags          permission
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["permission:write"]
+// @Param         id         path string true "Role id"
+// @Param         permission path string true "Permission code"
+// @Success       200
+// @Router        /role/{id}/permission/{permission} [delete]
+func (rcv *PermissionController) RolePermissionRevoke(c *gin.Context) {
+	uri := &exchange.RolePermissionUri{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.permissionService.RolePermissionRevoke(uri)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
 	"brickwall/cmd/api/service"
 	"brickwall/internal/common"
 )
@@ -21,6 +22,11 @@ type IRoleController interface {
 	RoleSelectByID(*gin.Context)
 	RoleUpdateByID(*gin.Context)
 	RoleDeleteByID(*gin.Context)
+
+	// Business operations
+	UserRoleSelect(*gin.Context)
+	UserRoleGrant(*gin.Context)
+	UserRoleRevoke(*gin.Context)
 }
 
 type RoleController struct {
@@ -38,14 +44,38 @@ func NewRoleController(ctx context.Context, grp *gin.RouterGroup) IRoleControlle
 }
 
 func (rcv *RoleController) Register() {
+	// renaming a role changes every grant, so the catalog stays with SYS whatever
+	// the policy grants, the assignments need ADMIN on top of the permission
+	sys := middleware.RequireRoles()
+	admin := middleware.RequireRoles(common.RoleAdmin)
+	write := middleware.RequirePermission(common.PermRoleWrite)
+	read := middleware.RequirePermission(common.PermRoleRead)
+	assign := middleware.RequirePermission(common.PermRoleAssign)
+
 	// CRUD operations
-	rcv.group.POST("/role", rcv.RoleNew)
+	rcv.group.POST("/role", sys, write, rcv.RoleNew)
 	rcv.group.GET("/role", rcv.RoleSelect)
 	rcv.group.GET("/role/:id", rcv.RoleSelectByID)
-	rcv.group.PUT("/role", rcv.RoleUpdateByID)
-	rcv.group.DELETE("/role/:id", rcv.RoleDeleteByID)
+	rcv.group.PUT("/role", sys, write, rcv.RoleUpdateByID)
+	rcv.group.DELETE("/role/:id", sys, write, rcv.RoleDeleteByID)
+
+	// Business operations
+	rcv.group.GET("/user/:id/role", admin, read, rcv.UserRoleSelect)
+	rcv.group.POST("/user/:id/role", admin, assign, rcv.UserRoleGrant)
+	rcv.group.DELETE("/user/:id/role/:role", admin, assign, rcv.UserRoleRevoke)
 }
 
+// @Summary       Create role
+// @Description   Add the role to the catalog
+// @Tags          role
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["role:write"]
+// @Param         request body exchange.RoleNewReq true "Role"
+// @Success       201
+// @Router        /role [post]
 func (rcv *RoleController) RoleNew(c *gin.Context) {
 	req := &exchange.RoleNewReq{}
 
@@ -93,6 +123,17 @@ func (rcv *RoleController) RoleSelectByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Update role
+// @Description   Rename the role, every grant follows the new name
+// @Tags          role
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["role:write"]
+// @Param         request body exchange.RoleUpdateReq true "Role"
+// @Success       200
+// @Router        /role [put]
 func (rcv *RoleController) RoleUpdateByID(c *gin.Context) {
 	req := &exchange.RoleUpdateReq{}
 
@@ -108,6 +149,17 @@ func (rcv *RoleController) RoleUpdateByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Delete role
+// @Description   Remove the role and all of its grants
+// @Tags          role
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["role:write"]
+// @Param         id path string true "Role id"
+// @Success       200
+// @Router        /role/{id} [delete]
 func (rcv *RoleController) RoleDeleteByID(c *gin.Context) {
 	uri := &exchange.RoleUriID{}
 
@@ -123,3 +175,92 @@ func (rcv *RoleController) RoleDeleteByID(c *gin.Context) {
 		gin.H{"message": "no data"}),
 	)
 }
+
+// @Summary       List user roles
+// @Description   Return the roles assigned to the user
+// @Tags          role
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["role:read"]
+// @Param         id path string true "User id"
+// @Success       200
+// @Router        /user/{id}/role [get]
+func (rcv *RoleController) UserRoleSelect(c *gin.Context) {
+	uri := &exchange.UserUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.roleService.UserRoleSelect(uri.ID)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Grant user role
+// @Description   Assign the role to the user, only SYS may grant SYS
+// @Tags          role
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["role:assign"]
+// @Param         id      path string                    true "User id"
+// @Param         request body exchange.UserRoleGrantReq true "Role"
+// @Success       200
+// @Router        /user/{id}/role [post]
+func (rcv *RoleController) UserRoleGrant(c *gin.Context) {
+	uri := &exchange.UserUriID{}
+	req := &exchange.UserRoleGrantReq{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.roleService.UserRoleGrant(principal, uri.ID, req)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Revoke user role
+// @Description   Remove the role from the user, only SYS may revoke SYS
+// @Tags          role
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["role:assign"]
+// @Param         id   path string true "User id"
+// @Param         role path string true "Role name"
+// @Success       200
+// @Router        /user/{id}/role/{role} [delete]
+func (rcv *RoleController) UserRoleRevoke(c *gin.Context) {
+	uri := &exchange.UserRoleUri{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.roleService.UserRoleRevoke(principal, uri)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
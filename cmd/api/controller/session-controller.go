@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
+)
+
+type ISessionController interface {
+	common.IController
+
+	SessionSelect(*gin.Context)
+	SessionRevoke(*gin.Context)
+}
+
+// SessionController lets the admins see and revoke the signed-in devices of
+// any user, the users manage their own ones through the auth controller
+type SessionController struct {
+	ctx         context.Context
+	group       *gin.RouterGroup
+	authService service.IAuthService
+}
+
+func NewSessionController(ctx context.Context, grp *gin.RouterGroup) ISessionController {
+	serviceManager := ctx.Value(common.KeyServiceManager).(service.IServiceManager)
+
+	return &SessionController{
+		ctx: ctx, group: grp, authService: serviceManager.AuthService(),
+	}
+}
+
+func (rcv *SessionController) Register() {
+	read := middleware.RequirePermission(common.PermSessionRead)
+	revoke := middleware.RequirePermission(common.PermSessionRevoke)
+
+	rcv.group.GET("/user/:id/sessions", read, rcv.SessionSelect)
+	rcv.group.DELETE("/user/:id/sessions/:session", revoke, rcv.SessionRevoke)
+}
+
+// @Summary       List user sessions
+// @Description   Return the signed-in devices of the user
+// @Tags          session
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["session:read"]
+// @Param         id path string true "User id"
+// @Success       200
+// @Router        /user/{id}/sessions [get]
+func (rcv *SessionController) SessionSelect(c *gin.Context) {
+	uri := &exchange.UserUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	res, err := rcv.authService.SessionSelect(uri.ID)
+	if err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(res))
+}
+
+// @Summary       Revoke user session
+// @Description   Sign the device out, the tokens of the session are rejected immediately
+// @Tags          session
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["session:revoke"]
+// @Param         id      path string true "User id"
+// @Param         session path string true "Session id"
+// @Success       200
+// @Router        /user/{id}/sessions/{session} [delete]
+func (rcv *SessionController) SessionRevoke(c *gin.Context) {
+	uri := &exchange.AuthSessionUri{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	if err := rcv.authService.SessionRevoke(uri.ID, uri.Session); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
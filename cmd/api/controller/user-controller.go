@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"brickwall/cmd/api/exchange"
+	"brickwall/cmd/api/middleware"
 	"brickwall/cmd/api/service"
 	"brickwall/internal/common"
 )
@@ -25,6 +26,7 @@ type IUserController interface {
 	UserUpdateCredentialsByID(*gin.Context)
 	UserUpdateIsBlockedByID(*gin.Context)
 	UserUpdateIsCheckedByID(*gin.Context)
+	UserUnlockByID(*gin.Context)
 }
 
 type UserController struct {
@@ -42,15 +44,35 @@ func NewUserController(ctx context.Context, grp *gin.RouterGroup) IUserControlle
 }
 
 func (rcv *UserController) Register() {
+	read := middleware.RequirePermission(common.PermUserRead)
+	write := middleware.RequirePermission(common.PermUserWrite)
+	block := middleware.RequirePermission(common.PermUserBlock)
+	remove := middleware.RequirePermission(common.PermUserDelete)
+
 	// CRUD operations
-	rcv.group.GET("/user", rcv.UserSelect)
-	rcv.group.GET("/user/:id", rcv.UserSelectByID)
+	rcv.group.GET("/user", read, rcv.UserSelect)
+	rcv.group.GET("/user/:id", read, rcv.UserSelectByID)
+	// owners change their own credentials, the service decides on the target user
 	rcv.group.PUT("/user/section/credentials", rcv.UserUpdateCredentialsByID)
-	rcv.group.PUT("/user/section/is_blocked", rcv.UserUpdateIsBlockedByID)
-	rcv.group.PUT("/user/section/is_checked", rcv.UserUpdateIsCheckedByID)
-	rcv.group.DELETE("/user/:id", rcv.UserDeleteByID)
+	rcv.group.PUT("/user/section/is_blocked", block, rcv.UserUpdateIsBlockedByID)
+	rcv.group.PUT("/user/section/is_checked", write, rcv.UserUpdateIsCheckedByID)
+	rcv.group.DELETE("/user/:id", remove, rcv.UserDeleteByID)
+	rcv.group.DELETE("/user/:id/lockout", block, rcv.UserUnlockByID)
 }
 
+// @Summary       List users
+// @Description   Return the user page
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:read"]
+// @Param         page  query int    true  "Page"
+// @Param         size  query int    true  "Page size"
+// @Param         order query string false "Order"
+// @Success       200
+// @Router        /user [get]
 func (rcv *UserController) UserSelect(c *gin.Context) {
 	qry := &exchange.UserQuery{}
 
@@ -68,6 +90,17 @@ func (rcv *UserController) UserSelect(c *gin.Context) {
 	)
 }
 
+// @Summary       Get user
+// @Description   Return the user by id
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:read"]
+// @Param         id path string true "User id"
+// @Success       200
+// @Router        /user/{id} [get]
 func (rcv *UserController) UserSelectByID(c *gin.Context) {
 	uri := &exchange.UserUriID{}
 
@@ -98,6 +131,17 @@ func (rcv *UserController) UserSelectByUsername(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Delete user
+// @Description   Remove the user account
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:delete"]
+// @Param         id path string true "User id"
+// @Success       200
+// @Router        /user/{id} [delete]
 func (rcv *UserController) UserDeleteByID(c *gin.Context) {
 	uri := &exchange.UserUriID{}
 
@@ -105,7 +149,9 @@ func (rcv *UserController) UserDeleteByID(c *gin.Context) {
 		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
 		return
 	}
-	if err := rcv.userService.UserDeleteByID(uri.ID); err != nil {
+	principal, _ := middleware.Principal(c)
+
+	if err := rcv.userService.UserDeleteByID(principal, uri.ID); err != nil {
 		c.JSON(common.ErrMapper(err))
 		return
 	}
@@ -115,6 +161,18 @@ func (rcv *UserController) UserDeleteByID(c *gin.Context) {
 }
 
 // Buseness operations
+
+// @Summary       Update credentials
+// @Description   Change the username and password of the own user, or of another user with user:write
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:write"]
+// @Param         request body exchange.UserUpdateCredentialsReq true "Credentials"
+// @Success       200
+// @Router        /user/section/credentials [put]
 func (rcv *UserController) UserUpdateCredentialsByID(c *gin.Context) {
 	req := &exchange.UserUpdateCredentialsReq{}
 
@@ -122,7 +180,9 @@ func (rcv *UserController) UserUpdateCredentialsByID(c *gin.Context) {
 		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
 		return
 	}
-	res, err := rcv.userService.UserUpdateCredentialsByID(req)
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.userService.UserUpdateCredentialsByID(principal, req)
 	if err != nil {
 		c.JSON(common.ErrMapper(err))
 		return
@@ -130,6 +190,17 @@ func (rcv *UserController) UserUpdateCredentialsByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Block user
+// @Description   Block or unblock the user account
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:block"]
+// @Param         request body exchange.UserUpdateIsBlockedByIDReq true "Blocked flag"
+// @Success       200
+// @Router        /user/section/is_blocked [put]
 func (rcv *UserController) UserUpdateIsBlockedByID(c *gin.Context) {
 	req := &exchange.UserUpdateIsBlockedByIDReq{}
 
@@ -137,7 +208,9 @@ func (rcv *UserController) UserUpdateIsBlockedByID(c *gin.Context) {
 		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
 		return
 	}
-	res, err := rcv.userService.UserUpdateIsBlockedByID(req)
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.userService.UserUpdateIsBlockedByID(principal, req)
 	if err != nil {
 		c.JSON(common.ErrMapper(err))
 		return
@@ -145,6 +218,17 @@ func (rcv *UserController) UserUpdateIsBlockedByID(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
 
+// @Summary       Check user
+// @Description   Mark the user account as checked
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:write"]
+// @Param         request body exchange.UserUpdateIsCheckedByIDReq true "Checked flag"
+// @Success       200
+// @Router        /user/section/is_checked [put]
 func (rcv *UserController) UserUpdateIsCheckedByID(c *gin.Context) {
 	req := &exchange.UserUpdateIsCheckedByIDReq{}
 
@@ -152,10 +236,41 @@ func (rcv *UserController) UserUpdateIsCheckedByID(c *gin.Context) {
 		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
 		return
 	}
-	res, err := rcv.userService.UserUpdateIsCheckedByID(req)
+	principal, _ := middleware.Principal(c)
+
+	res, err := rcv.userService.UserUpdateIsCheckedByID(principal, req)
 	if err != nil {
 		c.JSON(common.ErrMapper(err))
 		return
 	}
 	c.JSON(http.StatusOK, common.NewResponse(res))
 }
+
+// @Summary       Unlock user
+// @Description   Lift the sign-in lockout after too many failed sign-ins
+// @Tags          user
+// @Accept        json
+// @Produce       json
+// @Security      Bearer
+// @Security      ApiKey
+// @x-permissions ["user:block"]
+// @Param         id path string true "User id"
+// @Success       200
+// @Router        /user/{id}/lockout [delete]
+func (rcv *UserController) UserUnlockByID(c *gin.Context) {
+	uri := &exchange.UserUriID{}
+
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.JSON(common.ErrMapper(fmt.Errorf("%w: %v", common.ErrReqBindJson, err)))
+		return
+	}
+	principal, _ := middleware.Principal(c)
+
+	if err := rcv.userService.UserUnlockByID(principal, uri.ID); err != nil {
+		c.JSON(common.ErrMapper(err))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewResponse(
+		gin.H{"message": "no data"}),
+	)
+}
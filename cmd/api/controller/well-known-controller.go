@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
+)
+
+type IWellKnownController interface {
+	common.IController
+
+	Jwks(*gin.Context)
+}
+
+type WellKnownController struct {
+	ctx         context.Context
+	group       *gin.RouterGroup
+	authService service.IAuthService
+}
+
+func NewWellKnownController(ctx context.Context, grp *gin.RouterGroup) IWellKnownController {
+	serviceManager := ctx.Value(common.KeyServiceManager).(service.IServiceManager)
+
+	return &WellKnownController{
+		ctx: ctx, group: grp, authService: serviceManager.AuthService(),
+	}
+}
+
+func (rcv *WellKnownController) Register() {
+	rcv.group.GET("/jwks.json", rcv.Jwks)
+}
+
+// Jwks publishes the public keys to verify the platform issued tokens
+func (rcv *WellKnownController) Jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, rcv.authService.Jwks())
+}
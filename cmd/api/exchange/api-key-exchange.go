@@ -0,0 +1,30 @@
+package exchange
+
+import (
+	"time"
+)
+
+type ApiKeyUri struct {
+	ID  string `uri:"id" binding:"required,max=32,alphanum"`
+	Key string `uri:"key" binding:"required,max=32,alphanum"`
+}
+
+type ServiceAccountNewReq struct {
+	Username string `json:"username" binding:"required,min=1,max=64"`
+}
+
+type ApiKeyNewReq struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required,max=64"`
+	ExpiresAt *time.Time `json:"expires_at" binding:"omitempty,gt"`
+}
+
+// ApiKeyNewRes carries the plain key, it is shown once and cannot be recovered
+type ApiKeyNewRes struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
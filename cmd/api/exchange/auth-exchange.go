@@ -1,16 +1,23 @@
 package exchange
 
-import "github.com/jackc/pgx/v5/pgtype"
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
 type AuthSignupReq struct {
 	Email     string `json:"email" binding:"required,email,max=255"`
-	Password  string `json:"password" binding:"required,min=4,max=72"`
+	Password  string `json:"password" binding:"required,max=72"`
 	Firstname string `json:"firstname" binding:"required,min=1,max=255"`
 	Lastname  string `json:"lastname" binding:"required,min=1,max=255"`
 }
 type AuthSigninReq struct {
 	Username string `json:"username" binding:"required,min=1,max=64"`
-	Password string `json:"password" binding:"required,min=4,max=72"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+type AuthSignoutReq struct {
+	Refresh string `json:"refresh" binding:"required"`
 }
 type AuthTokenRefreshReq struct {
 	Token string `json:"token" binding:"required"`
@@ -18,11 +25,83 @@ type AuthTokenRefreshReq struct {
 type AuthTokenInvalidateReq struct {
 	Token string `json:"token" binding:"required"`
 }
+type AuthVerifyReq struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+type AuthVerifyResendReq struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
 type AuthPasswordResetReq struct {
-	Password string `json:"password" binding:"required,min=4,max=72"`
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+type AuthPasswordResetConfirmReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,max=72"`
 }
 type AuthPasswordChangeReq struct {
-	Password string `json:"password" binding:"required,min=4,max=72"`
+	Current        string `json:"current" binding:"required,max=72"`
+	Password       string `json:"password" binding:"required,max=72,nefield=Current"`
+	RevokeSessions bool   `json:"revoke_sessions"`
+}
+type AuthMagicLinkReq struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+type AuthMagicLinkConsumeReq struct {
+	Token string `json:"token" binding:"required"`
+}
+type AuthMagicLinkToggleReq struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+type Auth2FACodeReq struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+type Auth2FAVerifyReq struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code,omitempty,max=32"`
+}
+type AuthOidcUri struct {
+	Provider string `uri:"provider" binding:"required,max=64"`
+}
+type AuthOidcCallbackReq struct {
+	Code  string `json:"code" binding:"required,max=2048"`
+	State string `json:"state" binding:"required,max=128"`
+}
+type AuthIdentityUriID struct {
+	ID string `uri:"id" binding:"required,max=32,alphanum"`
+}
+type AuthWebAuthnRegisterFinishReq struct {
+	Name       string          `json:"name" binding:"max=64"`
+	State      string          `json:"state" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+type AuthWebAuthnFinishReq struct {
+	State      string          `json:"state" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+type AuthWebAuthn2FABeginReq struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+type AuthWebAuthn2FAFinishReq struct {
+	Challenge  string          `json:"challenge" binding:"required"`
+	State      string          `json:"state" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+type AuthWebAuthnUriID struct {
+	ID string `uri:"id" binding:"required,max=32,alphanum"`
+}
+type AuthSessionUriID struct {
+	ID string `uri:"id" binding:"required,max=32,alphanum"`
+}
+type AuthSessionUri struct {
+	ID      string `uri:"id" binding:"required,max=32,alphanum"`
+	Session string `uri:"session" binding:"required,max=32,alphanum"`
+}
+
+// AuthDevice describes the client signing in, recorded with the session
+type AuthDevice struct {
+	UserAgent string
+	IpAddress string
 }
 
 // responses
@@ -38,7 +117,61 @@ type AuthTokens struct {
 	Access  string `json:"access"`
 	Refresh string `json:"refresh"`
 }
+type AuthChallenge struct {
+	Token     string   `json:"token"`
+	ExpiresIn int      `json:"expires_in"`
+	Methods   []string `json:"methods"`
+}
 type AuthSigninRes struct {
-	User   *AuthUser   `json:"user"`
-	Tokens *AuthTokens `json:"tokens"`
+	User      *AuthUser      `json:"user,omitempty"`
+	Tokens    *AuthTokens    `json:"tokens,omitempty"`
+	Challenge *AuthChallenge `json:"challenge,omitempty"`
+}
+type Auth2FAEnrollRes struct {
+	Secret        string   `json:"secret"`
+	Url           string   `json:"url"`
+	QRCode        string   `json:"qrcode"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+type Auth2FARecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type AuthMeProfile struct {
+	ID        string      `json:"id"`
+	Firstname string      `json:"firstname"`
+	Lastname  string      `json:"lastname"`
+	Gender    string      `json:"gender"`
+	Birthday  pgtype.Date `json:"birthday"`
+	AvatarUrl string      `json:"avatar_url"`
+	Enable2fa bool        `json:"enable_2fa"`
+}
+type AuthMeContact struct {
+	ID      string `json:"id"`
+	Class   string `json:"class"`
+	Content string `json:"content"`
+}
+type AuthMeRes struct {
+	ID          string           `json:"id"`
+	Username    string           `json:"username"`
+	IsBlocked   bool             `json:"is_blocked"`
+	IsChecked   bool             `json:"is_checked"`
+	Profile     *AuthMeProfile   `json:"profile"`
+	Contacts    []*AuthMeContact `json:"contacts"`
+	Roles       []string         `json:"roles"`
+	Permissions []string         `json:"permissions"`
+}
+type AuthOidcProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+type AuthOidcStartRes struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthWebAuthnBeginRes carries the options for navigator.credentials, the state
+// is sent back with the authenticator response
+type AuthWebAuthnBeginRes struct {
+	State   string `json:"state"`
+	Options any    `json:"options"`
 }
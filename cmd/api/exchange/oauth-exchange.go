@@ -0,0 +1,99 @@
+package exchange
+
+type OAuthClientUriID struct {
+	ID string `uri:"id" binding:"required,max=32,alphanum"`
+}
+
+type OAuthClientNewReq struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	IsPublic     bool     `json:"is_public"`
+	RedirectUris []string `json:"redirect_uris" binding:"omitempty,dive,url"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1,dive,oneof=authorization_code refresh_token client_credentials"`
+	Scopes       []string `json:"scopes" binding:"omitempty,dive,required,max=64"`
+	UserID       string   `json:"user_id" binding:"omitempty,max=32,alphanum"`
+}
+
+// OAuthClientNewRes carries the plain client secret, it is shown once and cannot be recovered
+type OAuthClientNewRes struct {
+	ID           string   `json:"client_id"`
+	Secret       string   `json:"client_secret,omitempty"`
+	Name         string   `json:"name"`
+	IsPublic     bool     `json:"is_public"`
+	RedirectUris []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	UserID       string   `json:"user_id,omitempty"`
+}
+
+// OAuthAuthorizeReq is the RFC 6749 authorization request, PKCE with S256 is mandatory
+type OAuthAuthorizeReq struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required,eq=code"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required,max=32"`
+	RedirectUri         string `form:"redirect_uri" json:"redirect_uri" binding:"required,url"`
+	Scope               string `form:"scope" json:"scope" binding:"max=1024"`
+	State               string `form:"state" json:"state" binding:"max=1024"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required,min=43,max=128"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required,eq=S256"`
+}
+
+type OAuthAuthorizeConsentReq struct {
+	OAuthAuthorizeReq
+	Approve bool `json:"approve"`
+}
+
+type OAuthAuthorizeClient struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// OAuthAuthorizeRes either asks for the user consent or carries the redirect back to the client
+type OAuthAuthorizeRes struct {
+	Client          *OAuthAuthorizeClient `json:"client"`
+	Scopes          []string              `json:"scopes"`
+	ConsentRequired bool                  `json:"consent_required"`
+	RedirectTo      string                `json:"redirect_to,omitempty"`
+}
+
+// OAuthTokenReq is the form encoded RFC 6749 token request of every supported grant
+type OAuthTokenReq struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectUri  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type OAuthTokenRes struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthIntrospectReq is the form encoded RFC 7662 introspection request
+type OAuthIntrospectReq struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// OAuthIntrospectRes is the RFC 7662 introspection response, an inactive token
+// carries no other member. Roles extend the registered members.
+type OAuthIntrospectRes struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
@@ -0,0 +1,31 @@
+package exchange
+
+type PermissionUriID struct {
+	ID string `uri:"id" binding:"required,max=32,alphanum"`
+}
+
+type PermissionNewReq struct {
+	Code        string `json:"code" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+type PermissionUpdateReq struct {
+	ID          string `json:"id" binding:"required,max=32"`
+	Code        string `json:"code" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+type PermissionQuery struct {
+	Page  int    `form:"page" binding:"required,min=1,numeric"`
+	Size  int    `form:"size" binding:"required,min=5,max=100,numeric"`
+	Order string `form:"order" binding:"omitempty,oneof=id code"`
+}
+
+type RolePermissionUri struct {
+	ID         string `uri:"id" binding:"required,max=32,alphanum"`
+	Permission string `uri:"permission" binding:"required,max=64"`
+}
+
+type RolePermissionGrantReq struct {
+	Permission string `json:"permission" binding:"required,max=64"`
+}
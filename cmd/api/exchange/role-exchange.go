@@ -18,3 +18,12 @@ type RoleQuery struct {
 	Size  int    `form:"size" binding:"required,min=5,max=100,numeric"`
 	Order string `form:"order" binding:"omitempty,oneof=id name iso2 iso3 num_code"`
 }
+
+type UserRoleUri struct {
+	ID   string `uri:"id" binding:"required,max=32,alphanum"`
+	Role string `uri:"role" binding:"required,max=255"`
+}
+
+type UserRoleGrantReq struct {
+	Role string `json:"role" binding:"required,max=255"`
+}
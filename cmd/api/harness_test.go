@@ -0,0 +1,687 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/urfave/cli/v3"
+
+	"brickwall/cmd/api"
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
+	"brickwall/internal/provider"
+	"brickwall/internal/storage/dbs"
+)
+
+// harness serves the api routes over http with the real providers, redis is
+// served by miniredis and the database by the in-memory fakeDB
+type harness struct {
+	t      *testing.T
+	ctx    context.Context
+	db     *fakeDB
+	redis  *miniredis.Miniredis
+	server *httptest.Server
+}
+
+func newHarness(t *testing.T, args ...string) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := &harness{t: t, db: newFakeDB(t), redis: miniredis.RunT(t)}
+
+	command := api.Command(context.Background())
+	command.Action = func(ctx context.Context, cli *cli.Command) error {
+		return h.serve(context.WithValue(ctx, common.KeyCommand, cli))
+	}
+	args = append([]string{
+		"api",
+		"--redis-addr", h.redis.Addr(),
+		"--jwt-private-key", writeSigningKey(t),
+		"--password-argon2-memory", "64",
+		"--password-argon2-iterations", "1",
+	}, args...)
+	if err := command.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+// serve wires the providers the way api.Serve does, without the database pool
+func (rcv *harness) serve(ctx context.Context) error {
+	redisProvider := provider.NewRedisProvider(ctx)
+	if _, err := redisProvider.Open(); err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyRedisProvider, redisProvider)
+	rcv.t.Cleanup(func() { redisProvider.Close() })
+
+	// transactions are not faked, the flows under test do not open one
+	ctx = context.WithValue(ctx, common.KeyPgxProvider, provider.NewPgxProvider(ctx))
+
+	jwtProvider := provider.NewJwtProvider(ctx)
+	if err := jwtProvider.LoadKeys(); err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyJwtProvider, jwtProvider)
+	ctx = context.WithValue(ctx, common.Key2FAProvider, provider.New2FAProvider())
+
+	mailerProvider, err := provider.NewMailerProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyMailerProvider, mailerProvider)
+	oidcProvider, err := provider.NewOidcProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyOidcProvider, oidcProvider)
+	webAuthnProvider, err := provider.NewWebAuthnProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyWebAuthnProvider, webAuthnProvider)
+	passwordPolicyProvider, err := provider.NewPasswordPolicyProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyPasswordPolicy, passwordPolicyProvider)
+	passwordHasherProvider, err := provider.NewPasswordHasherProvider(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyPasswordHasher, passwordHasherProvider)
+
+	routerProvider, err := provider.NewRouterProvider(ctx).Init()
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, common.KeyRouterProvider, routerProvider)
+	ctx = context.WithValue(ctx, common.KeyServiceManager, newServiceManager(ctx, dbs.New(rcv.db)))
+	ctx = context.WithValue(ctx, common.KeyValidatorProvider, validator.New())
+
+	api.RegisterRoutes(ctx, routerProvider)
+	rcv.ctx = ctx
+	rcv.server = httptest.NewServer(routerProvider.Engine())
+	return nil
+}
+
+func (rcv *harness) hasher() provider.IPasswordHasherProvider {
+	return rcv.ctx.Value(common.KeyPasswordHasher).(provider.IPasswordHasherProvider)
+}
+
+// addUser stores a checked user with the password and roles
+func (rcv *harness) addUser(id, username, password string, roles ...string) *fakeUser {
+	rcv.t.Helper()
+
+	hash, err := rcv.hasher().Hash(password)
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	user := &fakeUser{ID: id, Username: username, Password: hash, IsChecked: true, Roles: roles}
+	rcv.db.users[id] = user
+	return user
+}
+
+// signin returns the access token of the password sign-in
+func (rcv *harness) signin(username, password string) string {
+	rcv.t.Helper()
+
+	return signedIn(rcv.t, rcv.do(http.MethodPost, "/api/v1/auth/signin", "", map[string]string{
+		"username": username, "password": password,
+	}))
+}
+
+// signedIn returns the access token of the completed sign-in, a challenge fails the test
+func signedIn(t *testing.T, res *response) string {
+	t.Helper()
+
+	var body struct {
+		Content struct {
+			Tokens *struct {
+				Access string `json:"access"`
+			} `json:"tokens"`
+		} `json:"content"`
+	}
+	res.status(http.StatusOK).decode(&body)
+	if body.Content.Tokens == nil || body.Content.Tokens.Access == "" {
+		t.Fatalf("sign-in returned no tokens: %s", res.body)
+	}
+	return body.Content.Tokens.Access
+}
+
+// do sends the json body, the authorization is sent as is
+func (rcv *harness) do(method, path, authorization string, body any) *response {
+	rcv.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			rcv.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, rcv.server.URL+path, reader)
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return rcv.send(req, authorization)
+}
+
+// form sends the form encoded body, as the oauth endpoints expect it
+func (rcv *harness) form(path, authorization string, values url.Values) *response {
+	rcv.t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, rcv.server.URL+path, strings.NewReader(values.Encode()))
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return rcv.send(req, authorization)
+}
+
+func (rcv *harness) send(req *http.Request, authorization string) *response {
+	rcv.t.Helper()
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	res, err := rcv.server.Client().Do(req)
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	return &response{t: rcv.t, code: res.StatusCode, header: res.Header, body: raw}
+}
+
+type response struct {
+	t      *testing.T
+	code   int
+	header http.Header
+	body   []byte
+}
+
+func (rcv *response) status(code int) *response {
+	rcv.t.Helper()
+
+	if rcv.code != code {
+		rcv.t.Fatalf("status %d, want %d: %s", rcv.code, code, rcv.body)
+	}
+	return rcv
+}
+
+func (rcv *response) decode(v any) {
+	rcv.t.Helper()
+
+	if err := json.Unmarshal(rcv.body, v); err != nil {
+		rcv.t.Fatalf("%v: %s", err, rcv.body)
+	}
+}
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+func writeSigningKey(t *testing.T) string {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "jwt.key")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// serviceManager builds the services on the given queries, the way
+// service.NewServiceManager does on the database pool
+type serviceManager struct {
+	aux        service.IAuxService
+	user       service.IUserService
+	auth       service.IAuthService
+	principal  service.IPrincipalService
+	apiKey     service.IApiKeyService
+	oauth      service.IOAuthService
+	role       service.IRoleService
+	permission service.IPermissionService
+	country    service.ICountryService
+	currency   service.ICurrencyService
+}
+
+func newServiceManager(ctx context.Context, queries *dbs.Queries) service.IServiceManager {
+	permission := service.NewPermissionService(ctx, queries)
+	return &serviceManager{
+		aux:        service.NewAuxService(ctx, queries),
+		user:       service.NewUserService(ctx, queries),
+		auth:       service.NewAuthService(ctx, queries),
+		principal:  service.NewPrincipalService(ctx, queries),
+		apiKey:     service.NewApiKeyService(ctx, queries),
+		oauth:      service.NewOAuthService(ctx, queries),
+		role:       service.NewRoleService(ctx, queries, permission),
+		permission: permission,
+		country:    service.NewCountryService(ctx, queries),
+		currency:   service.NewCurrencyService(ctx, queries),
+	}
+}
+
+func (rcv *serviceManager) AuxService() service.IAuxService               { return rcv.aux }
+func (rcv *serviceManager) UserService() service.IUserService             { return rcv.user }
+func (rcv *serviceManager) AuthService() service.IAuthService             { return rcv.auth }
+func (rcv *serviceManager) PrincipalService() service.IPrincipalService   { return rcv.principal }
+func (rcv *serviceManager) ApiKeyService() service.IApiKeyService         { return rcv.apiKey }
+func (rcv *serviceManager) OAuthService() service.IOAuthService           { return rcv.oauth }
+func (rcv *serviceManager) RoleService() service.IRoleService             { return rcv.role }
+func (rcv *serviceManager) PermissionService() service.IPermissionService { return rcv.permission }
+func (rcv *serviceManager) CountryService() service.ICountryService       { return rcv.country }
+func (rcv *serviceManager) CurrencyService() service.ICurrencyService     { return rcv.currency }
+
+// fakeDB answers the sqlc queries by name from in-memory tables. A query
+// returns rows of structs, scanned field by field in the sqlc column order,
+// or of single values.
+type fakeDB struct {
+	t          *testing.T
+	users      map[string]*fakeUser
+	policy     map[string][]string
+	clients    map[string]*dbs.OauthClient
+	consents   map[string][]string
+	identities map[string]*dbs.UserIdentity
+	passkeys   []*fakePasskey
+	handlers   map[string]func(args []any) ([]any, error)
+}
+
+type fakePasskey struct {
+	ID           string
+	UserID       string
+	CredentialID []byte
+	Credential   []byte
+}
+
+type fakeUser struct {
+	ID        string
+	Username  string
+	Password  string
+	IsBlocked bool
+	IsChecked bool
+	Roles     []string
+	Enable2fa bool
+}
+
+func newFakeDB(t *testing.T) *fakeDB {
+	db := &fakeDB{
+		t:          t,
+		users:      map[string]*fakeUser{},
+		policy:     map[string][]string{},
+		clients:    map[string]*dbs.OauthClient{},
+		consents:   map[string][]string{},
+		identities: map[string]*dbs.UserIdentity{},
+	}
+	db.handlers = map[string]func(args []any) ([]any, error){
+		"UserSelectByID": func(args []any) ([]any, error) {
+			user, ok := db.users[args[0].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return []any{&dbs.UserSelectByIDRow{
+				ID: user.ID, Username: user.Username, IsBlocked: user.IsBlocked, IsChecked: user.IsChecked,
+			}}, nil
+		},
+		"UserRoleSelectByUserID": func(args []any) ([]any, error) {
+			rows := []any{}
+			if user, ok := db.users[args[0].(string)]; ok {
+				for _, role := range user.Roles {
+					rows = append(rows, &dbs.Role{ID: role, Name: role})
+				}
+			}
+			return rows, nil
+		},
+		"UserRoleSelectUserIDByRoleID": func(args []any) ([]any, error) {
+			rows := []any{}
+			for _, user := range db.users {
+				if slices.Contains(user.Roles, args[0].(string)) {
+					rows = append(rows, user.ID)
+				}
+			}
+			return rows, nil
+		},
+		// the role ids are the role names, a rename changes both
+		"RoleUpdateByID": func(args []any) ([]any, error) {
+			name, id := args[0].(string), args[1].(string)
+			for _, user := range db.users {
+				if index := slices.Index(user.Roles, id); index >= 0 {
+					user.Roles[index] = name
+				}
+			}
+			db.policy[name] = db.policy[id]
+			delete(db.policy, id)
+			return []any{&dbs.Role{ID: name, Name: name}}, nil
+		},
+		"RoleDeleteByID": func(args []any) ([]any, error) {
+			id := args[0].(string)
+			for _, user := range db.users {
+				user.Roles = slices.DeleteFunc(user.Roles, func(role string) bool { return role == id })
+			}
+			delete(db.policy, id)
+			return []any{id}, nil
+		},
+		"RolePermissionSelectPolicy": func(args []any) ([]any, error) {
+			rows := []any{}
+			for role, permissions := range db.policy {
+				for _, permission := range permissions {
+					rows = append(rows, &dbs.RolePermissionSelectPolicyRow{Role: role, Permission: permission})
+				}
+			}
+			return rows, nil
+		},
+		"UserUpdateCredentialsByID": func(args []any) ([]any, error) {
+			user, ok := db.users[args[2].(string)]
+			if !ok {
+				return nil, nil
+			}
+			user.Username, user.Password = args[0].(string), args[1].(string)
+			return []any{&dbs.UserUpdateCredentialsByIDRow{
+				ID: user.ID, Username: user.Username, IsBlocked: user.IsBlocked, IsChecked: user.IsChecked,
+			}}, nil
+		},
+		"UserUpdateIsCheckedByID": func(args []any) ([]any, error) {
+			user, ok := db.users[args[1].(string)]
+			if !ok {
+				return nil, nil
+			}
+			user.IsChecked = args[0].(bool)
+			return []any{&dbs.UserUpdateIsCheckedByIDRow{
+				ID: user.ID, Username: user.Username, IsBlocked: user.IsBlocked, IsChecked: user.IsChecked,
+			}}, nil
+		},
+		"AuthSelectUserCredentials": func(args []any) ([]any, error) {
+			user := db.userByUsername(args[0].(string))
+			if user == nil {
+				return nil, nil
+			}
+			return []any{&dbs.AuthSelectUserCredentialsRow{
+				ID: user.ID, Username: user.Username, Password: user.Password,
+				IsBlocked: user.IsBlocked, IsChecked: user.IsChecked,
+			}}, nil
+		},
+		"AuthUpdatePasswordByID": func(args []any) ([]any, error) {
+			user, ok := db.users[args[0].(string)]
+			if !ok {
+				return nil, nil
+			}
+			user.Password = args[1].(string)
+			return []any{user.ID}, nil
+		},
+		"AuthUpdateVisitedAt": func(args []any) ([]any, error) {
+			user, ok := db.users[args[0].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return []any{&dbs.AuthUpdateVisitedAtRow{ID: user.ID, Username: user.Username}}, nil
+		},
+		"ProfileSelectByUserID": func(args []any) ([]any, error) {
+			user, ok := db.users[args[0].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return []any{&dbs.Profile{ID: user.ID, UserID: user.ID, Enable2fa: user.Enable2fa}}, nil
+		},
+		"AuthSelectMe": func(args []any) ([]any, error) {
+			user, ok := db.users[args[0].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return []any{&dbs.VUserProfile{
+				UserID: user.ID, ProfileID: pgtype.Text{String: user.ID, Valid: true},
+				UserUsername: user.Username, UserIsBlocked: user.IsBlocked, UserIsChecked: user.IsChecked,
+				ProfileEnable2fa: pgtype.Bool{Bool: user.Enable2fa, Valid: true},
+				ProfileSecret2fa: pgtype.Text{String: "JBSWY3DPEHPK3PXP", Valid: true},
+			}}, nil
+		},
+		// the usernames are the email contacts of the users
+		"ContactSelectByUserID": func(args []any) ([]any, error) {
+			user, ok := db.users[args[0].(string)]
+			if !ok {
+				return []any{}, nil
+			}
+			return []any{&dbs.Contact{ID: "c" + user.ID, UserID: user.ID, Class: "email", Content: user.Username}}, nil
+		},
+		"WebauthnCredentialCountByUserID": func(args []any) ([]any, error) {
+			return []any{int64(len(db.userPasskeys(args[0].(string))))}, nil
+		},
+		"WebauthnCredentialSelectCredentialByUserID": func(args []any) ([]any, error) {
+			rows := []any{}
+			for _, passkey := range db.userPasskeys(args[0].(string)) {
+				rows = append(rows, passkey.Credential)
+			}
+			return rows, nil
+		},
+		"WebauthnCredentialNew": func(args []any) ([]any, error) {
+			passkey := &fakePasskey{
+				ID:     fmt.Sprintf("w%d", len(db.passkeys)+1),
+				UserID: args[0].(string), CredentialID: args[2].([]byte), Credential: args[3].([]byte),
+			}
+			db.passkeys = append(db.passkeys, passkey)
+			return []any{&dbs.WebauthnCredentialNewRow{ID: passkey.ID, UserID: passkey.UserID, Name: args[1].(string)}}, nil
+		},
+		"WebauthnCredentialUpdateByCredentialID": func(args []any) ([]any, error) {
+			for _, passkey := range db.userPasskeys(args[1].(string)) {
+				if bytes.Equal(passkey.CredentialID, args[2].([]byte)) {
+					passkey.Credential = args[0].([]byte)
+					return []any{passkey.ID}, nil
+				}
+			}
+			return nil, nil
+		},
+		"UserSessionDeleteExpiredByUserID": func(args []any) ([]any, error) {
+			return nil, nil
+		},
+		"UserSessionNew": func(args []any) ([]any, error) {
+			return nil, nil
+		},
+		"UserSessionDeleteByFamilyID": func(args []any) ([]any, error) {
+			return nil, nil
+		},
+		// the usernames are the email contacts of the users
+		"AuthSelectUserByEmail": func(args []any) ([]any, error) {
+			user := db.userByUsername(args[0].(string))
+			if user == nil {
+				return nil, nil
+			}
+			return []any{&dbs.AuthSelectUserByEmailRow{
+				ID: user.ID, Username: user.Username, IsBlocked: user.IsBlocked, IsChecked: user.IsChecked,
+			}}, nil
+		},
+		"UserIdentitySelectByProviderSubject": func(args []any) ([]any, error) {
+			for _, identity := range db.identities {
+				if identity.Provider == args[0].(string) && identity.Subject == args[1].(string) {
+					return []any{&dbs.UserIdentitySelectByProviderSubjectRow{
+						ID: identity.ID, UserID: identity.UserID, Provider: identity.Provider,
+						Subject: identity.Subject, Email: identity.Email,
+					}}, nil
+				}
+			}
+			return nil, nil
+		},
+		"UserIdentityNew": func(args []any) ([]any, error) {
+			identity := &dbs.UserIdentity{
+				ID:     fmt.Sprintf("i%d", len(db.identities)+1),
+				UserID: args[0].(string), Provider: args[1].(string), Subject: args[2].(string), Email: args[3].(string),
+			}
+			db.identities[identity.ID] = identity
+			return []any{&dbs.UserIdentityNewRow{
+				ID: identity.ID, UserID: identity.UserID, Provider: identity.Provider,
+				Subject: identity.Subject, Email: identity.Email,
+			}}, nil
+		},
+		"UserIdentityUpdateLastUsedAtByID": func(args []any) ([]any, error) {
+			identity, ok := db.identities[args[1].(string)]
+			if !ok {
+				return nil, nil
+			}
+			identity.Email = args[0].(string)
+			return []any{identity.ID}, nil
+		},
+		"OauthClientSelectByID": func(args []any) ([]any, error) {
+			client, ok := db.clients[args[0].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return []any{client}, nil
+		},
+		"OauthConsentSelectByUserIDClientID": func(args []any) ([]any, error) {
+			scopes, ok := db.consents[args[0].(string)+"/"+args[1].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return []any{&dbs.OauthConsent{UserID: args[0].(string), ClientID: args[1].(string), Scopes: scopes}}, nil
+		},
+		"OauthConsentUpsert": func(args []any) ([]any, error) {
+			db.consents[args[0].(string)+"/"+args[1].(string)] = args[2].([]string)
+			return nil, nil
+		},
+	}
+	return db
+}
+
+func (rcv *fakeDB) userByUsername(username string) *fakeUser {
+	for _, user := range rcv.users {
+		if user.Username == username {
+			return user
+		}
+	}
+	return nil
+}
+
+func (rcv *fakeDB) userPasskeys(userID string) []*fakePasskey {
+	passkeys := []*fakePasskey{}
+	for _, passkey := range rcv.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys
+}
+
+func (rcv *fakeDB) run(sql string, args []any) ([]any, error) {
+	name := strings.Fields(strings.TrimPrefix(sql, "-- name: "))[0]
+	handler, ok := rcv.handlers[name]
+	if !ok {
+		rcv.t.Errorf("fake db: unexpected query %s", name)
+		return nil, fmt.Errorf("unexpected query %s", name)
+	}
+	return handler(args)
+}
+
+func (rcv *fakeDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	rows, err := rcv.run(sql, args)
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", len(rows))), err
+}
+
+func (rcv *fakeDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := rcv.run(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows, index: -1}, nil
+}
+
+func (rcv *fakeDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	rows, err := rcv.run(sql, args)
+	return &fakeRow{rows: rows, err: err}
+}
+
+type fakeRow struct {
+	rows []any
+	err  error
+}
+
+func (rcv *fakeRow) Scan(dest ...any) error {
+	if rcv.err != nil {
+		return rcv.err
+	}
+	if len(rcv.rows) == 0 {
+		return pgx.ErrNoRows
+	}
+	return scan(rcv.rows[0], dest)
+}
+
+type fakeRows struct {
+	rows  []any
+	index int
+}
+
+func (rcv *fakeRows) Close()                                       {}
+func (rcv *fakeRows) Err() error                                   { return nil }
+func (rcv *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (rcv *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (rcv *fakeRows) Values() ([]any, error)                       { return nil, nil }
+func (rcv *fakeRows) RawValues() [][]byte                          { return nil }
+func (rcv *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (rcv *fakeRows) Next() bool {
+	rcv.index++
+	return rcv.index < len(rcv.rows)
+}
+
+func (rcv *fakeRows) Scan(dest ...any) error {
+	return scan(rcv.rows[rcv.index], dest)
+}
+
+// scan copies the row into the destinations, a struct row fills one destination per field
+func scan(row any, dest []any) error {
+	values := []reflect.Value{reflect.ValueOf(row)}
+	if value := reflect.Indirect(values[0]); value.Kind() == reflect.Struct {
+		values = values[:0]
+		for i := 0; i < value.NumField(); i++ {
+			values = append(values, value.Field(i))
+		}
+	}
+	if len(values) != len(dest) {
+		return fmt.Errorf("fake db: %d values for %d destinations", len(values), len(dest))
+	}
+	for i, value := range values {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if !value.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("fake db: cannot scan %s into %s", value.Type(), target.Type())
+		}
+		target.Set(value)
+	}
+	return nil
+}
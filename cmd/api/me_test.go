@@ -0,0 +1,42 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"brickwall/internal/common"
+)
+
+func TestAuthMe(t *testing.T) {
+	h := newHarness(t)
+	h.db.policy[common.RoleEditor] = []string{common.PermUserRead}
+	h.addUser("u1", "alice@example.com", "Secret123", common.RoleEditor)
+	session := bearer(h.signin("alice@example.com", "Secret123"))
+
+	var body struct {
+		Content struct {
+			ID      string `json:"id"`
+			Profile *struct {
+				ID string `json:"id"`
+			} `json:"profile"`
+			Contacts    []map[string]string `json:"contacts"`
+			Roles       []string            `json:"roles"`
+			Permissions []string            `json:"permissions"`
+		} `json:"content"`
+	}
+	res := h.do(http.MethodGet, "/api/v1/auth/me", session, nil)
+	res.status(http.StatusOK).decode(&body)
+
+	me := body.Content
+	if me.ID != "u1" || me.Profile == nil || len(me.Contacts) != 1 || me.Contacts[0]["content"] != "alice@example.com" {
+		t.Fatalf("unexpected identity: %s", res.body)
+	}
+	if len(me.Roles) != 1 || me.Roles[0] != common.RoleEditor || len(me.Permissions) != 1 || me.Permissions[0] != common.PermUserRead {
+		t.Fatalf("unexpected roles: %s", res.body)
+	}
+	// the secret of the view never reaches the response
+	if strings.Contains(string(res.body), "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("2fa secret leaked: %s", res.body)
+	}
+}
@@ -1,40 +1,134 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"brickwall/cmd/api/service"
+	"brickwall/internal/common"
 	"brickwall/internal/provider"
 )
 
-func AuthMiddleware(jwt provider.IJwtProvider) gin.HandlerFunc {
+const (
+	KeyUserID    = "user_id"
+	KeyRoles     = "roles"
+	KeyToken     = "token"
+	KeyClaims    = "claims"
+	KeyApiKey    = "api_key"
+	KeyPrincipal = "principal"
+)
+
+// AuthMiddleware accepts access tokens and api keys and loads the principal of
+// the token subject or key owner with the permissions of its roles, blocked users
+// are rejected even while their credentials are valid.
+func AuthMiddleware(ctx context.Context) gin.HandlerFunc {
+	return authMiddleware(ctx, true)
+}
+
+// SessionMiddleware is the AuthMiddleware for routes acting on the signed-in
+// session itself, they accept access tokens issued to the user only.
+func SessionMiddleware(ctx context.Context) gin.HandlerFunc {
+	return authMiddleware(ctx, false)
+}
+
+func authMiddleware(ctx context.Context, acceptApiKeys bool) gin.HandlerFunc {
+	jwtProvider := ctx.Value(common.KeyJwtProvider).(provider.IJwtProvider)
+	serviceManager := ctx.Value(common.KeyServiceManager).(service.IServiceManager)
+	principalService := serviceManager.PrincipalService()
+	permissionService := serviceManager.PermissionService()
+	apiKeyService := serviceManager.ApiKeyService()
+
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid token"})
-			c.Abort()
-			return
+		var (
+			userID string
+			scopes []string
+		)
+		if key, ok := ApiKey(c); ok && acceptApiKeys {
+			apiKey, err := apiKeyService.Authenticate(key)
+			if err != nil {
+				c.AbortWithStatusJSON(common.ErrMapper(err))
+				return
+			}
+			userID, scopes = apiKey.UserID, apiKey.Scopes
+			c.Set(KeyApiKey, apiKey.ID)
+		} else {
+			tokenString, ok := BearerToken(c)
+			if !ok {
+				c.AbortWithStatusJSON(common.ErrMapper(
+					fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "missing bearer token"),
+				))
+				return
+			}
+			claims, err := jwtProvider.ValidateToken(tokenString)
+			if err != nil {
+				c.AbortWithStatusJSON(common.ErrMapper(err))
+				return
+			}
+			if claims.Type != provider.TokenTypeAccess {
+				c.AbortWithStatusJSON(common.ErrMapper(
+					fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type),
+				))
+				return
+			}
+			// tokens issued to OAuth clients are limited to the granted scope
+			if claims.ClientID != "" {
+				if !acceptApiKeys {
+					c.AbortWithStatusJSON(common.ErrMapper(
+						fmt.Errorf("%w: %v", common.ErrAuthForbidden, "client tokens cannot act on the session"),
+					))
+					return
+				}
+				scopes = strings.Fields(claims.Scope)
+			}
+			userID = claims.UserID()
+			c.Set(KeyToken, tokenString)
+			c.Set(KeyClaims, claims)
 		}
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := jwt.ValidateToken(tokenString)
+		principal, err := principalService.Principal(userID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			if errors.Is(err, common.ErrDBNotFound) {
+				err = fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "unknown user")
+			}
+			c.AbortWithStatusJSON(common.ErrMapper(err))
 			return
 		}
-		// Заглушка: проверка статуса пользователя и его ролей из БД (можно заменить реальным вызовом)
-		userStatus := "active"        // допустим, получили из БД
-		userRoles := []string{"user"} // допустим, получили из БД
-
-		if userStatus != "active" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "user is blocked"})
-			c.Abort()
+		if principal.IsBlocked {
+			c.AbortWithStatusJSON(common.ErrMapper(
+				fmt.Errorf("%w: %v", common.ErrAuthForbidden, common.ErrAuthUserBlocked),
+			))
 			return
 		}
-		c.Set("user_id", claims.UserID)
-		c.Set("roles", userRoles)
+		if principal.Permissions, err = permissionService.Permissions(principal.Roles); err != nil {
+			c.AbortWithStatusJSON(common.ErrMapper(err))
+			return
+		}
+		principal.Scopes = scopes
+
+		c.Set(KeyUserID, principal.UserID)
+		c.Set(KeyRoles, principal.Roles)
+		c.Set(KeyPrincipal, principal)
 		c.Next()
 	}
 }
+
+// BearerToken extracts the token from the Authorization header
+func BearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(authHeader, "Bearer "), true
+}
+
+// ApiKey extracts the api key from the Authorization header
+func ApiKey(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "ApiKey ") {
+		return "", false
+	}
+	return strings.TrimPrefix(authHeader, "ApiKey "), true
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/internal/common"
+)
+
+// RequirePermission lets the request through when the principal holds all of
+// the permissions, SYS passes every check. It must run after the AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := Principal(c)
+		if !ok {
+			c.AbortWithStatusJSON(common.ErrMapper(
+				fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "missing principal"),
+			))
+			return
+		}
+		if !principal.HasPermission(permissions...) {
+			c.AbortWithStatusJSON(common.ErrMapper(
+				fmt.Errorf("%w: %v permissions required", common.ErrAuthForbidden, permissions),
+			))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"brickwall/internal/common"
+)
+
+// RequireRoles lets the request through when the principal holds any of the
+// roles, SYS passes every check. It must run after the AuthMiddleware.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	allowed := append([]string{common.RoleSys}, roles...)
+
+	return func(c *gin.Context) {
+		principal, ok := Principal(c)
+		if !ok {
+			c.AbortWithStatusJSON(common.ErrMapper(
+				fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "missing principal"),
+			))
+			return
+		}
+		if !principal.HasRole(allowed...) {
+			c.AbortWithStatusJSON(common.ErrMapper(
+				fmt.Errorf("%w: one of %v roles required", common.ErrAuthForbidden, allowed),
+			))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Principal returns the principal loaded by the AuthMiddleware
+func Principal(c *gin.Context) (*common.Principal, bool) {
+	value, ok := c.Get(KeyPrincipal)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*common.Principal)
+	return principal, ok
+}
@@ -0,0 +1,288 @@
+package api_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/internal/common"
+	"brickwall/internal/storage/dbs"
+)
+
+const (
+	oauthRedirectUri  = "https://app.example.com/callback"
+	oauthVerifier     = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	oauthClientSecret = "service-secret"
+)
+
+// newOAuthHarness registers the admin user, the public client of an app, a
+// public client without scopes and the confidential client of a service account
+func newOAuthHarness(t *testing.T) *harness {
+	h := newHarness(t)
+
+	h.db.policy[common.RoleAdmin] = []string{common.PermUserRead, common.PermUserWrite}
+	h.db.policy["SERVICE"] = []string{common.PermTokenIntrospect}
+	h.addUser("u1", "alice@example.com", "Secret123", common.RoleAdmin)
+	h.addUser("s1", "introspector", "Secret123", "SERVICE")
+
+	h.db.clients["app"] = &dbs.OauthClient{
+		ID: "app", Name: "App", IsPublic: true,
+		RedirectUris: []string{oauthRedirectUri},
+		GrantTypes:   []string{"authorization_code", "refresh_token"},
+		Scopes:       []string{common.PermUserRead},
+	}
+	h.db.clients["bare"] = &dbs.OauthClient{
+		ID: "bare", Name: "Bare", IsPublic: true,
+		RedirectUris: []string{oauthRedirectUri},
+		GrantTypes:   []string{"authorization_code"},
+		Scopes:       []string{},
+	}
+	h.db.clients["svc"] = &dbs.OauthClient{
+		ID: "svc", Name: "Service", SecretHash: common.HashToken(oauthClientSecret),
+		GrantTypes: []string{"client_credentials"},
+		Scopes:     []string{common.PermTokenIntrospect},
+		UserID:     pgtype.Text{String: "s1", Valid: true},
+	}
+	return h
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeReq(clientID, scope string) exchange.OAuthAuthorizeReq {
+	return exchange.OAuthAuthorizeReq{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectUri:         oauthRedirectUri,
+		Scope:               scope,
+		State:               "af0ifjsldkj",
+		CodeChallenge:       challenge(oauthVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+// authorize returns the code the user approved, the consent is given when asked for
+func authorize(t *testing.T, h *harness, session, clientID, scope string) string {
+	t.Helper()
+
+	req := authorizeReq(clientID, scope)
+	query := url.Values{
+		"response_type":         {req.ResponseType},
+		"client_id":             {req.ClientID},
+		"redirect_uri":          {req.RedirectUri},
+		"scope":                 {req.Scope},
+		"state":                 {req.State},
+		"code_challenge":        {req.CodeChallenge},
+		"code_challenge_method": {req.CodeChallengeMethod},
+	}
+	var body struct {
+		Content exchange.OAuthAuthorizeRes `json:"content"`
+	}
+	h.do(http.MethodGet, "/api/v1/oauth/authorize?"+query.Encode(), bearer(session), nil).status(http.StatusOK).decode(&body)
+
+	if body.Content.ConsentRequired {
+		consent := &exchange.OAuthAuthorizeConsentReq{OAuthAuthorizeReq: req, Approve: true}
+		h.do(http.MethodPost, "/api/v1/oauth/authorize", bearer(session), consent).status(http.StatusOK).decode(&body)
+	}
+	redirectTo, err := url.Parse(body.Content.RedirectTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redirectTo.Query().Get("state") != req.State {
+		t.Fatalf("state not returned: %s", body.Content.RedirectTo)
+	}
+	code := redirectTo.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code: %s", body.Content.RedirectTo)
+	}
+	return code
+}
+
+func exchangeCode(h *harness, clientID, code, verifier string) *response {
+	return h.form("/api/v1/oauth/token", "", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientID},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectUri},
+		"code_verifier": {verifier},
+	})
+}
+
+func refreshToken(h *harness, clientID, token string) *response {
+	return h.form("/api/v1/oauth/token", "", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {clientID},
+		"refresh_token": {token},
+	})
+}
+
+func clientCredentials(h *harness, secret string) *response {
+	return h.form("/api/v1/oauth/token", "", url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"svc"},
+		"client_secret": {secret},
+	})
+}
+
+func oauthError(t *testing.T, res *response, code int, error string) {
+	t.Helper()
+
+	exception := &common.OAuthException{}
+	res.status(code).decode(exception)
+	if exception.Error != error {
+		t.Fatalf("error %q, want %q", exception.Error, error)
+	}
+}
+
+func TestOAuthAuthorizationCode(t *testing.T) {
+	h := newOAuthHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	// the first request asks for the consent, it is remembered afterwards
+	code := authorize(t, h, session, "app", common.PermUserRead)
+	if scopes := h.db.consents["u1/app"]; len(scopes) != 1 || scopes[0] != common.PermUserRead {
+		t.Fatalf("consent not recorded: %v", scopes)
+	}
+
+	tokens := &exchange.OAuthTokenRes{}
+	exchangeCode(h, "app", code, oauthVerifier).status(http.StatusOK).decode(tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.Scope != common.PermUserRead {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	}
+
+	// codes are single use
+	oauthError(t, exchangeCode(h, "app", code, oauthVerifier), http.StatusBadRequest, "invalid_grant")
+}
+
+func TestOAuthAuthorizationCodeWrongVerifier(t *testing.T) {
+	h := newOAuthHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	code := authorize(t, h, session, "app", "")
+	oauthError(t, exchangeCode(h, "app", code, "wrong-verifier-wrong-verifier-wrong-verifier"), http.StatusBadRequest, "invalid_grant")
+
+	// the rejected attempt consumed the code
+	oauthError(t, exchangeCode(h, "app", code, oauthVerifier), http.StatusBadRequest, "invalid_grant")
+
+	// a code is bound to the client it was issued to
+	code = authorize(t, h, session, "app", "")
+	oauthError(t, exchangeCode(h, "bare", code, oauthVerifier), http.StatusBadRequest, "invalid_grant")
+}
+
+func TestOAuthRefreshRotation(t *testing.T) {
+	h := newOAuthHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	issued := &exchange.OAuthTokenRes{}
+	exchangeCode(h, "app", authorize(t, h, session, "app", ""), oauthVerifier).status(http.StatusOK).decode(issued)
+
+	rotated := &exchange.OAuthTokenRes{}
+	refreshToken(h, "app", issued.RefreshToken).status(http.StatusOK).decode(rotated)
+	if rotated.RefreshToken == "" || rotated.RefreshToken == issued.RefreshToken || rotated.Scope != issued.Scope {
+		t.Fatalf("refresh token not rotated: %+v", rotated)
+	}
+
+	// replaying the old refresh token revokes the whole family
+	oauthError(t, refreshToken(h, "app", issued.RefreshToken), http.StatusBadRequest, "invalid_grant")
+	oauthError(t, refreshToken(h, "app", rotated.RefreshToken), http.StatusBadRequest, "invalid_grant")
+}
+
+func TestOAuthRefreshAfterConsentRevoked(t *testing.T) {
+	h := newOAuthHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	issued := &exchange.OAuthTokenRes{}
+	exchangeCode(h, "app", authorize(t, h, session, "app", ""), oauthVerifier).status(http.StatusOK).decode(issued)
+
+	delete(h.db.consents, "u1/app")
+	oauthError(t, refreshToken(h, "app", issued.RefreshToken), http.StatusBadRequest, "invalid_grant")
+}
+
+func TestOAuthClientCredentials(t *testing.T) {
+	h := newOAuthHarness(t)
+
+	oauthError(t, clientCredentials(h, "wrong-secret"), http.StatusUnauthorized, "invalid_client")
+
+	tokens := &exchange.OAuthTokenRes{}
+	clientCredentials(h, oauthClientSecret).status(http.StatusOK).decode(tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken != "" || tokens.Scope != common.PermTokenIntrospect {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	}
+
+	// the public client cannot use the grant
+	res := h.form("/api/v1/oauth/token", "", url.Values{"grant_type": {"client_credentials"}, "client_id": {"app"}})
+	oauthError(t, res, http.StatusBadRequest, "unauthorized_client")
+}
+
+func TestOAuthIntrospect(t *testing.T) {
+	h := newOAuthHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	issued := &exchange.OAuthTokenRes{}
+	exchangeCode(h, "app", authorize(t, h, session, "app", ""), oauthVerifier).status(http.StatusOK).decode(issued)
+
+	service := &exchange.OAuthTokenRes{}
+	clientCredentials(h, oauthClientSecret).status(http.StatusOK).decode(service)
+
+	introspected := &exchange.OAuthIntrospectRes{}
+	h.form("/api/v1/oauth/introspect", bearer(service.AccessToken), url.Values{"token": {issued.AccessToken}}).
+		status(http.StatusOK).decode(introspected)
+	if !introspected.Active || introspected.ClientID != "app" || introspected.Username != "alice@example.com" ||
+		introspected.Scope != common.PermUserRead || introspected.TokenType != "access_token" {
+		t.Fatalf("unexpected introspection: %+v", introspected)
+	}
+
+	// the tokens of blocked users are inactive
+	h.db.users["u1"].IsBlocked = true
+	introspected = &exchange.OAuthIntrospectRes{}
+	h.form("/api/v1/oauth/introspect", bearer(service.AccessToken), url.Values{"token": {issued.AccessToken}}).
+		status(http.StatusOK).decode(introspected)
+	if introspected.Active || introspected.Username != "" {
+		t.Fatalf("blocked user token is active: %+v", introspected)
+	}
+
+	introspected = &exchange.OAuthIntrospectRes{}
+	h.form("/api/v1/oauth/introspect", bearer(service.AccessToken), url.Values{"token": {"garbage"}}).
+		status(http.StatusOK).decode(introspected)
+	if introspected.Active {
+		t.Fatal("garbage token is active")
+	}
+
+	// introspection requires the permission
+	h.form("/api/v1/oauth/introspect", bearer(session), url.Values{"token": {issued.AccessToken}}).status(http.StatusForbidden)
+}
+
+// TestOAuthClientTokenOwnerWrite checks the client tokens cannot change the
+// credentials of the user who authorized them, whatever their scope
+func TestOAuthClientTokenOwnerWrite(t *testing.T) {
+	h := newOAuthHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	scoped := &exchange.OAuthTokenRes{}
+	exchangeCode(h, "app", authorize(t, h, session, "app", ""), oauthVerifier).status(http.StatusOK).decode(scoped)
+	unscoped := &exchange.OAuthTokenRes{}
+	exchangeCode(h, "bare", authorize(t, h, session, "bare", ""), oauthVerifier).status(http.StatusOK).decode(unscoped)
+	if unscoped.Scope != "" {
+		t.Fatalf("unexpected scope: %q", unscoped.Scope)
+	}
+
+	credentials := &exchange.UserUpdateCredentialsReq{ID: "u1", Username: "mallory@example.com", Password: "Takeover123"}
+	for name, token := range map[string]string{"scoped": scoped.AccessToken, "unscoped": unscoped.AccessToken} {
+		t.Run(name, func(t *testing.T) {
+			h.do(http.MethodPut, "/api/v1/user/section/credentials", bearer(token), credentials).status(http.StatusForbidden)
+		})
+	}
+	if user := h.db.users["u1"]; user.Username != "alice@example.com" {
+		t.Fatalf("credentials changed to %s", user.Username)
+	}
+
+	// the client tokens cannot act on the session either
+	h.do(http.MethodGet, "/api/v1/oauth/consent", bearer(scoped.AccessToken), nil).status(http.StatusForbidden)
+}
@@ -0,0 +1,277 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"brickwall/internal/common"
+)
+
+const oidcClientID = "brickwall"
+
+// mockIdP is the OpenID provider, the test plays the browser and approves the
+// authorization request with the claims of the ID token
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*idpGrant
+}
+
+type idpGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{t: t, key: key, codes: map[string]*idpGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+	mux.HandleFunc("POST /token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (rcv *mockIdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                rcv.server.URL,
+		"authorization_endpoint":                rcv.server.URL + "/authorize",
+		"token_endpoint":                        rcv.server.URL + "/token",
+		"jwks_uri":                              rcv.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (rcv *mockIdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(rcv.key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rcv.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token redeems the code once, the verifier must match the challenge
+func (rcv *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	grant, ok := rcv.codes[r.FormValue("code")]
+	delete(rcv.codes, r.FormValue("code"))
+	rcv.mu.Unlock()
+
+	if !ok || challenge(r.FormValue("code_verifier")) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": rcv.server.URL,
+		"aud": oidcClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	idToken, err := token.SignedString(rcv.key)
+	if err != nil {
+		rcv.t.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "idp-access", "token_type": "Bearer", "expires_in": 60, "id_token": idToken,
+	})
+}
+
+// authorize approves the authorization url the api redirected to and returns
+// the code and the state for the callback, the claims carry the requested
+// nonce unless they set one
+func (rcv *mockIdP) authorize(redirectTo string, claims jwt.MapClaims) (string, string) {
+	rcv.t.Helper()
+
+	location, err := url.Parse(redirectTo)
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	query := location.Query()
+	if query.Get("client_id") != oidcClientID || query.Get("code_challenge_method") != "S256" {
+		rcv.t.Fatalf("unexpected authorization request: %s", redirectTo)
+	}
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+	code := common.HashToken(redirectTo)
+
+	rcv.mu.Lock()
+	rcv.codes[code] = &idpGrant{challenge: query.Get("code_challenge"), claims: claims}
+	rcv.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// config writes the providers file, mock links the identities by email and
+// strict refuses the taken emails
+func (rcv *mockIdP) config() string {
+	rcv.t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"providers": []map[string]any{
+			{"name": "mock", "issuer": rcv.server.URL, "client_id": oidcClientID, "client_secret": "secret", "link_by_email": true},
+			{"name": "strict", "issuer": rcv.server.URL, "client_id": oidcClientID, "client_secret": "secret"},
+		},
+	})
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	file := filepath.Join(rcv.t.TempDir(), "oidc.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		rcv.t.Fatal(err)
+	}
+	return file
+}
+
+func newOidcHarness(t *testing.T) (*harness, *mockIdP) {
+	idp := newMockIdP(t)
+	h := newHarness(t, "--oidc-config", idp.config())
+	h.addUser("u1", "alice@example.com", "Secret123", common.RoleAdmin)
+	return h, idp
+}
+
+// oidcStart begins the sign-in, or the link with the session, and returns the
+// authorization url of the provider
+func oidcStart(h *harness, path, session string) string {
+	h.t.Helper()
+
+	method := http.MethodGet
+	if session != "" {
+		method = http.MethodPost
+		session = bearer(session)
+	}
+	var body struct {
+		Content struct {
+			RedirectTo string `json:"redirect_to"`
+		} `json:"content"`
+	}
+	h.do(method, path, session, nil).status(http.StatusOK).decode(&body)
+	return body.Content.RedirectTo
+}
+
+func oidcCallback(h *harness, path, session, code, state string) *response {
+	if session != "" {
+		session = bearer(session)
+	}
+	return h.do(http.MethodPost, path, session, map[string]string{"code": code, "state": state})
+}
+
+func aliceClaims(subject string, verified any) jwt.MapClaims {
+	return jwt.MapClaims{"sub": subject, "email": "Alice@example.com", "email_verified": verified}
+}
+
+func TestOidcStateMismatch(t *testing.T) {
+	h, idp := newOidcHarness(t)
+
+	// unknown state
+	code, _ := idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), aliceClaims("sub-1", true))
+	oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, "forged-state").status(http.StatusUnauthorized)
+
+	// the state is bound to the provider it was started for, and is single use
+	code, state := idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), aliceClaims("sub-1", true))
+	oidcCallback(h, "/api/v1/auth/oidc/strict/callback", "", code, state).status(http.StatusUnauthorized)
+	oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state).status(http.StatusUnauthorized)
+
+	// the state of a link is not a sign-in
+	session := h.signin("alice@example.com", "Secret123")
+	code, state = idp.authorize(oidcStart(h, "/api/v1/auth/identity/mock", session), aliceClaims("sub-1", true))
+	oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state).status(http.StatusUnauthorized)
+
+	if len(h.db.identities) != 0 {
+		t.Fatalf("identity linked on a mismatched state: %v", h.db.identities)
+	}
+}
+
+func TestOidcNonceMismatch(t *testing.T) {
+	h, idp := newOidcHarness(t)
+
+	claims := aliceClaims("sub-1", true)
+	claims["nonce"] = "replayed-nonce"
+	code, state := idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), claims)
+	oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state).status(http.StatusUnauthorized)
+
+	if len(h.db.identities) != 0 {
+		t.Fatalf("identity linked on a mismatched nonce: %v", h.db.identities)
+	}
+}
+
+func TestOidcLinkExistingAccount(t *testing.T) {
+	h, idp := newOidcHarness(t)
+
+	// the provider without link by email refuses the taken email
+	code, state := idp.authorize(oidcStart(h, "/api/v1/auth/oidc/strict", ""), aliceClaims("sub-1", true))
+	oidcCallback(h, "/api/v1/auth/oidc/strict/callback", "", code, state).status(http.StatusForbidden)
+
+	// the first sign-in links the identity to the user with the verified email
+	code, state = idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), aliceClaims("sub-1", true))
+	signedIn(t, oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state))
+	if len(h.db.identities) != 1 || h.db.identities["i1"].UserID != "u1" || h.db.identities["i1"].Email != "alice@example.com" {
+		t.Fatalf("identity not linked to the user: %v", h.db.identities)
+	}
+
+	// the next sign-in finds the identity by the subject
+	code, state = idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), aliceClaims("sub-1", "true"))
+	signedIn(t, oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state))
+	if len(h.db.identities) != 1 {
+		t.Fatalf("identity linked twice: %v", h.db.identities)
+	}
+
+	// the signed-in user links the identity of the other provider
+	session := h.signin("alice@example.com", "Secret123")
+	code, state = idp.authorize(oidcStart(h, "/api/v1/auth/identity/strict", session), aliceClaims("sub-2", false))
+	oidcCallback(h, "/api/v1/auth/identity/strict/callback", session, code, state).status(http.StatusOK)
+	if len(h.db.identities) != 2 || h.db.identities["i2"].UserID != "u1" || h.db.identities["i2"].Provider != "strict" {
+		t.Fatalf("identity not linked to the signed-in user: %v", h.db.identities)
+	}
+}
+
+func TestOidcUnverifiedEmail(t *testing.T) {
+	h, idp := newOidcHarness(t)
+
+	// an unverified email does not take over the account with the same email
+	code, state := idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), aliceClaims("sub-1", false))
+	oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state).status(http.StatusForbidden)
+
+	// nor provisions a new user
+	claims := jwt.MapClaims{"sub": "sub-3", "email": "bob@example.com"}
+	code, state = idp.authorize(oidcStart(h, "/api/v1/auth/oidc/mock", ""), claims)
+	oidcCallback(h, "/api/v1/auth/oidc/mock/callback", "", code, state).status(http.StatusForbidden)
+
+	if len(h.db.identities) != 0 {
+		t.Fatalf("identity linked on an unverified email: %v", h.db.identities)
+	}
+}
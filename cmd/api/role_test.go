@@ -0,0 +1,35 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"brickwall/internal/common"
+)
+
+func newRoleHarness(t *testing.T) (*harness, string, string) {
+	h := newHarness(t)
+	h.db.policy[common.RoleEditor] = []string{common.PermUserRead}
+	h.addUser("u1", "alice@example.com", "Secret123", common.RoleEditor)
+	h.addUser("s1", "root@example.com", "Secret123", common.RoleSys)
+
+	// the first request caches the principal and the policy
+	session := bearer(h.signin("alice@example.com", "Secret123"))
+	h.do(http.MethodGet, "/api/v1/user/u1", session, nil).status(http.StatusOK)
+	return h, session, bearer(h.signin("root@example.com", "Secret123"))
+}
+
+func TestRoleRenameReloadsPrincipals(t *testing.T) {
+	h, session, root := newRoleHarness(t)
+
+	rename := map[string]string{"id": common.RoleEditor, "name": "WRITER"}
+	h.do(http.MethodPut, "/api/v1/role", root, rename).status(http.StatusOK)
+	h.do(http.MethodGet, "/api/v1/user/u1", session, nil).status(http.StatusOK)
+}
+
+func TestRoleDeleteReloadsPrincipals(t *testing.T) {
+	h, session, root := newRoleHarness(t)
+
+	h.do(http.MethodDelete, "/api/v1/role/"+common.RoleEditor, root, nil).status(http.StatusOK)
+	h.do(http.MethodGet, "/api/v1/user/u1", session, nil).status(http.StatusForbidden)
+}
@@ -6,13 +6,28 @@ import (
 	_ "brickwall/docs"
 
 	"brickwall/cmd/api/controller"
+	"brickwall/cmd/api/middleware"
+	"brickwall/internal/common"
 	"brickwall/internal/provider"
 
 	swaggerDoc "github.com/swaggo/files"
 	swaggerGin "github.com/swaggo/gin-swagger"
+	"github.com/urfave/cli/v3"
 )
 
 func RegisterRoutes(ctx context.Context, router provider.IRouterProvider) {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	// The auth routes are served by the standalone auth service when configured,
+	// otherwise the api embeds them so a single binary is enough in development
+	embedded := cli.String("auth-service-url") == ""
+	if embedded {
+		// Well-known documents are served from the root by convention
+		controller.NewWellKnownController(ctx, router.Engine().Group("/.well-known")).Register()
+	} else {
+		controller.NewAuthProxyController(ctx, router.Engine()).Register()
+	}
+
 	api := router.Engine().Group("/api")
 	{
 		v1 := api.Group("/v1")
@@ -20,15 +35,22 @@ func RegisterRoutes(ctx context.Context, router provider.IRouterProvider) {
 			// Swagger docs
 			v1.GET("/docs/*any", swaggerGin.WrapHandler(swaggerDoc.Handler))
 
-			// TODO: Auth middleware !!!
-
-			// API controllers
+			// Public API controllers, the auth and oauth controllers protect their own routes
 			controller.NewAuxController(ctx, v1).Register()
-			controller.NewAuthController(ctx, v1).Register()
-			controller.NewUserController(ctx, v1).Register()
-			controller.NewRoleController(ctx, v1).Register()
-			controller.NewCountryController(ctx, v1).Register()
-			controller.NewCurrencyController(ctx, v1).Register()
+			if embedded {
+				controller.NewAuthController(ctx, v1).Register()
+				controller.NewOAuthController(ctx, v1).Register()
+			}
+
+			// Protected API controllers, accept bearer tokens and api keys
+			protected := v1.Group("", middleware.AuthMiddleware(ctx))
+			controller.NewUserController(ctx, protected).Register()
+			controller.NewRoleController(ctx, protected).Register()
+			controller.NewPermissionController(ctx, protected).Register()
+			controller.NewApiKeyController(ctx, protected).Register()
+			controller.NewSessionController(ctx, protected).Register()
+			controller.NewCountryController(ctx, protected).Register()
+			controller.NewCurrencyController(ctx, protected).Register()
 		}
 	}
 }
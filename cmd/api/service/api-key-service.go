@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/internal/common"
+
+	"brickwall/internal/storage/dbs"
+)
+
+// Api keys look like bsp_<prefix>_<secret>, the prefix is stored in clear to find
+// the key and the whole key is stored as a hash only
+const (
+	ApiKeyMarker = "bsp_"
+
+	apiKeyPrefixSize = 8
+	apiKeySecretSize = 32
+)
+
+type IApiKeyService interface {
+	// Service accounts
+	ServiceAccountNew(*exchange.ServiceAccountNewReq) (*dbs.ServiceAccountNewRow, error)
+	ServiceAccountSelect() ([]*dbs.ServiceAccountSelectRow, error)
+
+	// Api keys
+	ApiKeyNew(*common.Principal, string, *exchange.ApiKeyNewReq) (*exchange.ApiKeyNewRes, error)
+	ApiKeySelect(string) ([]*dbs.ApiKeySelectByUserIDRow, error)
+	ApiKeyRevoke(*exchange.ApiKeyUri) error
+
+	// Authentication
+	Authenticate(string) (*dbs.ApiKey, error)
+}
+
+type ApiKeyService struct {
+	ctx     context.Context
+	queries *dbs.Queries
+}
+
+func NewApiKeyService(ctx context.Context, queries *dbs.Queries) IApiKeyService {
+	return &ApiKeyService{
+		ctx: ctx, queries: queries,
+	}
+}
+
+// ServiceAccountNew creates a user that can authenticate with api keys only
+func (rcv *ApiKeyService) ServiceAccountNew(req *exchange.ServiceAccountNewReq) (*dbs.ServiceAccountNewRow, error) {
+	res, err := rcv.queries.ServiceAccountNew(context.Background(), req.Username)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return res, nil
+}
+
+func (rcv *ApiKeyService) ServiceAccountSelect() ([]*dbs.ServiceAccountSelectRow, error) {
+	res, err := rcv.queries.ServiceAccountSelect(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.ServiceAccountSelectRow{}
+	}
+	return res, nil
+}
+
+// ApiKeyNew issues a key for the service account, the scopes must exist and
+// the principal cannot hand out permissions it does not hold itself
+func (rcv *ApiKeyService) ApiKeyNew(principal *common.Principal, userID string, req *exchange.ApiKeyNewReq) (*exchange.ApiKeyNewRes, error) {
+	ctx := context.Background()
+
+	if _, err := rcv.serviceAccount(userID); err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if _, err := rcv.queries.PermissionSelectByCode(ctx, scope); err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, fmt.Errorf("%w: unknown scope %s", common.ErrDBNotFound, scope)
+			} else {
+				return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+			}
+		}
+	}
+	if !principal.HasPermission(req.Scopes...) {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthForbidden, "scopes exceed the granted permissions")
+	}
+	buf := make([]byte, apiKeyPrefixSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	prefix := hex.EncodeToString(buf)
+
+	secret, err := common.RandomToken(apiKeySecretSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	key := ApiKeyMarker + prefix + "_" + secret
+
+	params := &dbs.ApiKeyNewParams{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: common.HashToken(key),
+		Scopes:  nonNil(req.Scopes),
+	}
+	if req.ExpiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamp{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+	row, err := rcv.queries.ApiKeyNew(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return &exchange.ApiKeyNewRes{
+		ID:        row.ID,
+		Name:      row.Name,
+		Key:       key,
+		Prefix:    row.Prefix,
+		Scopes:    row.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, nil
+}
+
+func (rcv *ApiKeyService) ApiKeySelect(userID string) ([]*dbs.ApiKeySelectByUserIDRow, error) {
+	if _, err := rcv.serviceAccount(userID); err != nil {
+		return nil, err
+	}
+	res, err := rcv.queries.ApiKeySelectByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.ApiKeySelectByUserIDRow{}
+	}
+	return res, nil
+}
+
+func (rcv *ApiKeyService) ApiKeyRevoke(uri *exchange.ApiKeyUri) error {
+	_, err := rcv.queries.ApiKeyDeleteByUserIDID(context.Background(), &dbs.ApiKeyDeleteByUserIDIDParams{
+		UserID: uri.ID, ID: uri.Key,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	return nil
+}
+
+// Authenticate resolves the active key, unknown, malformed and expired keys are
+// reported alike. The last use is recorded at most once a minute.
+func (rcv *ApiKeyService) Authenticate(key string) (*dbs.ApiKey, error) {
+	ctx := context.Background()
+
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "invalid api key")
+	}
+	res, err := rcv.queries.ApiKeySelectActiveByPrefix(ctx, prefix)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "invalid api key")
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(res.KeyHash), []byte(common.HashToken(key))) != 1 {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "invalid api key")
+	}
+	if err := rcv.queries.ApiKeyUpdateLastUsedAtByID(ctx, res.ID); err != nil {
+		slog.Error("api key last use", "id", res.ID, "error", err)
+	}
+	return res, nil
+}
+
+func (rcv *ApiKeyService) serviceAccount(userID string) (*dbs.ServiceAccountSelectByIDRow, error) {
+	res, err := rcv.queries.ServiceAccountSelectByID(context.Background(), userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	return res, nil
+}
+
+func apiKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, ApiKeyMarker)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != hex.EncodedLen(apiKeyPrefixSize) || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
@@ -2,45 +2,212 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
 	"brickwall/cmd/api/exchange"
 	"brickwall/internal/common"
 	"brickwall/internal/provider"
 	"brickwall/internal/storage/dbs"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/jackc/pgx/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/urfave/cli/v3"
 )
 
 type IAuthService interface {
 	Signup(*exchange.AuthSignupReq) (*dbs.UserNewRow, error)
-	Signin(*exchange.AuthSigninReq) (*exchange.AuthSigninRes, error)
-	Signout() (bool, error)
+	Signin(*exchange.AuthSigninReq, *exchange.AuthDevice) (*exchange.AuthSigninRes, error)
+	Signout(string, *exchange.AuthSignoutReq) (*common.Message, error)
+	SignoutEverywhere(string) (*common.Message, error)
+	Refresh(*exchange.AuthTokenRefreshReq) (*exchange.AuthTokens, error)
+	Jwks() *provider.Jwks
+
+	// 2FA operations
+	TwoFAEnroll(string) (*exchange.Auth2FAEnrollRes, error)
+	TwoFAConfirm(string, *exchange.Auth2FACodeReq) (*common.Message, error)
+	TwoFADisable(string, *exchange.Auth2FACodeReq) (*common.Message, error)
+	TwoFAVerify(*exchange.Auth2FAVerifyReq, *exchange.AuthDevice) (*exchange.AuthSigninRes, error)
+	TwoFARecoveryCodes(string, *exchange.Auth2FACodeReq) (*exchange.Auth2FARecoveryCodesRes, error)
+
+	// email verification
+	Verify(*exchange.AuthVerifyReq) (*common.Message, error)
+	VerifyResend(*exchange.AuthVerifyResendReq) (*common.Message, error)
+
+	// password operations
+	PasswordReset(*exchange.AuthPasswordResetReq) (*common.Message, error)
+	PasswordResetConfirm(*exchange.AuthPasswordResetConfirmReq) (*common.Message, error)
+	PasswordChange(*provider.Claims, *exchange.AuthPasswordChangeReq) (*common.Message, error)
+
+	// passwordless sign-in
+	MagicLink(*exchange.AuthMagicLinkReq) (*common.Message, error)
+	MagicLinkConsume(*exchange.AuthMagicLinkConsumeReq, *exchange.AuthDevice) (*exchange.AuthSigninRes, error)
+	MagicLinkToggle(string, *exchange.AuthMagicLinkToggleReq) (*common.Message, error)
+
+	// federated sign-in
+	OidcProviders() []*exchange.AuthOidcProvider
+	OidcStart(string, string) (*exchange.AuthOidcStartRes, error)
+	OidcSignin(string, *exchange.AuthOidcCallbackReq, *exchange.AuthDevice) (*exchange.AuthSigninRes, error)
+	OidcLink(string, string, *exchange.AuthOidcCallbackReq) (*dbs.UserIdentityNewRow, error)
+	IdentitySelect(string) ([]*dbs.UserIdentitySelectByUserIDRow, error)
+	IdentityUnlink(string, string) error
+
+	// passkeys
+	WebAuthnRegisterBegin(string) (*exchange.AuthWebAuthnBeginRes, error)
+	WebAuthnRegisterFinish(string, *exchange.AuthWebAuthnRegisterFinishReq) (*dbs.WebauthnCredentialNewRow, error)
+	WebAuthnLoginBegin() (*exchange.AuthWebAuthnBeginRes, error)
+	WebAuthnLoginFinish(*exchange.AuthWebAuthnFinishReq, *exchange.AuthDevice) (*exchange.AuthSigninRes, error)
+	WebAuthn2FABegin(*exchange.AuthWebAuthn2FABeginReq) (*exchange.AuthWebAuthnBeginRes, error)
+	WebAuthn2FAFinish(*exchange.AuthWebAuthn2FAFinishReq, *exchange.AuthDevice) (*exchange.AuthSigninRes, error)
+	WebAuthnCredentialSelect(string) ([]*dbs.WebauthnCredentialSelectByUserIDRow, error)
+	WebAuthnCredentialDelete(string, string) error
+
+	// signed-in devices
+	SessionSelect(string) ([]*dbs.UserSessionSelectByUserIDRow, error)
+	SessionRevoke(string, string) error
+
+	Me(*common.Principal) (*exchange.AuthMeRes, error)
 }
+
+const (
+	keyChallengeAttempts = "auth:challenge:attempts:"
+	maxChallengeAttempts = 5
+
+	keyVerifyResend  = "auth:verify:resend:"
+	keyPasswordReset = "auth:reset:throttle:"
+	keyMagicLink     = "auth:magic-link:throttle:"
+	keyOidcState     = "auth:oidc:state:"
+	keyWebAuthnState = "auth:webauthn:state:"
+
+	keySigninFailures = "auth:signin:failures:"
+	keySigninLock     = "auth:signin:lock:"
+
+	oidcTokenSize = 32
+
+	webauthnCeremonyRegister = "register"
+	webauthnCeremonyLogin    = "login"
+	webauthnCeremony2FA      = "2fa"
+
+	ChallengeMethodTOTP     = "totp"
+	ChallengeMethodWebAuthn = "webauthn"
+
+	// unusablePassword never matches a password hash, users without a password
+	// sign in through their linked identities only
+	unusablePassword = "!"
+
+	ContactClassEmail = "email"
+)
+
 type AuthService struct {
 	ctx     context.Context
 	queries *dbs.Queries
+	redis   *redis.Client
+
+	challengeExpiration  time.Duration
+	verifyExpiration     time.Duration
+	verifyUrl            string
+	verifyResendInterval time.Duration
+	resetUrl             string
+	resetExpiration      time.Duration
+	resetInterval        time.Duration
+	magicLinkEnabled     bool
+	magicLinkUrl         string
+	magicLinkExpiration  time.Duration
+	magicLinkInterval    time.Duration
+	refreshExpiration    time.Duration
+	lockoutThreshold     int64
+	lockoutIpThreshold   int64
+	lockoutDuration      time.Duration
+	lockoutMaxDuration   time.Duration
+	lockoutWindow        time.Duration
+	oidcStateExpiration  time.Duration
+	webauthnExpiration   time.Duration
+
+	pgxProvider    provider.IPgxProvider
+	jwtProvider    provider.IJwtProvider
+	twoFAProvider  provider.I2FAProvider
+	mailerProvider provider.IMailerProvider
+	oidcProvider   provider.IOidcProvider
+	webauthn       provider.IWebAuthnProvider
+	passwordPolicy provider.IPasswordPolicyProvider
+	passwordHasher provider.IPasswordHasherProvider
+}
+
+// oidcState is the pending federated sign-in, or link when it carries the user
+type oidcState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	UserID   string `json:"user_id,omitempty"`
+}
 
-	pgxProvider   provider.IPgxProvider
-	jwtProvider   provider.IJwtProvider
-	twoFAProvider provider.I2FAProvider
+// webauthnState is the pending passkey ceremony, the second factor ceremony is
+// bound to the sign-in challenge it was started for
+type webauthnState struct {
+	Ceremony    string                `json:"ceremony"`
+	UserID      string                `json:"user_id,omitempty"`
+	ChallengeID string                `json:"challenge_id,omitempty"`
+	Session     *webauthn.SessionData `json:"session"`
 }
 
 func NewAuthService(ctx context.Context, queries *dbs.Queries) IAuthService {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
 	return &AuthService{
-		ctx:           ctx,
-		queries:       queries,
-		pgxProvider:   ctx.Value(common.KeyPgxProvider).(provider.IPgxProvider),
-		jwtProvider:   ctx.Value(common.KeyJwtProvider).(provider.IJwtProvider),
-		twoFAProvider: ctx.Value(common.Key2FAProvider).(provider.I2FAProvider),
+		ctx:     ctx,
+		queries: queries,
+		redis:   ctx.Value(common.KeyRedisProvider).(provider.IRedisProvider).Client(),
+
+		challengeExpiration:  cli.Duration("jwt-challenge-expiration"),
+		refreshExpiration:    cli.Duration("jwt-refresh-expiration"),
+		lockoutThreshold:     cli.Int("auth-lockout-threshold"),
+		lockoutIpThreshold:   cli.Int("auth-lockout-ip-threshold"),
+		lockoutDuration:      cli.Duration("auth-lockout-duration"),
+		lockoutMaxDuration:   cli.Duration("auth-lockout-max-duration"),
+		lockoutWindow:        cli.Duration("auth-lockout-window"),
+		verifyExpiration:     cli.Duration("jwt-verify-expiration"),
+		verifyUrl:            cli.String("auth-verify-url"),
+		verifyResendInterval: cli.Duration("auth-verify-resend-interval"),
+		resetUrl:             cli.String("auth-reset-url"),
+		resetExpiration:      cli.Duration("auth-reset-expiration"),
+		resetInterval:        cli.Duration("auth-reset-interval"),
+		magicLinkEnabled:     cli.Bool("auth-magic-link-enabled"),
+		magicLinkUrl:         cli.String("auth-magic-link-url"),
+		magicLinkExpiration:  cli.Duration("auth-magic-link-expiration"),
+		magicLinkInterval:    cli.Duration("auth-magic-link-interval"),
+		oidcStateExpiration:  cli.Duration("oidc-state-expiration"),
+		webauthnExpiration:   cli.Duration("webauthn-state-expiration"),
+
+		pgxProvider:    ctx.Value(common.KeyPgxProvider).(provider.IPgxProvider),
+		jwtProvider:    ctx.Value(common.KeyJwtProvider).(provider.IJwtProvider),
+		twoFAProvider:  ctx.Value(common.Key2FAProvider).(provider.I2FAProvider),
+		mailerProvider: ctx.Value(common.KeyMailerProvider).(provider.IMailerProvider),
+		oidcProvider:   ctx.Value(common.KeyOidcProvider).(provider.IOidcProvider),
+		webauthn:       ctx.Value(common.KeyWebAuthnProvider).(provider.IWebAuthnProvider),
+		passwordPolicy: ctx.Value(common.KeyPasswordPolicy).(provider.IPasswordPolicyProvider),
+		passwordHasher: ctx.Value(common.KeyPasswordHasher).(provider.IPasswordHasherProvider),
 	}
 }
 
 func (rcv *AuthService) Signup(req *exchange.AuthSignupReq) (*dbs.UserNewRow, error) {
 	ctx := context.Background()
 
+	if err := rcv.passwordPolicy.Validate(req.Password, req.Email); err != nil {
+		return nil, err
+	}
+	passwordCrypted, err := rcv.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrPasswordHash, err)
+	}
+
 	// begin new transaction
 	trx, err := rcv.pgxProvider.Pool().BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -54,11 +221,9 @@ func (rcv *AuthService) Signup(req *exchange.AuthSignupReq) (*dbs.UserNewRow, er
 	qtx := rcv.queries.WithTx(trx)
 
 	// create user
-	passwordCrypted, _ := bcrypt.GenerateFromPassword([]byte(req.Password), 12)
-
 	user, err := qtx.UserNew(context.Background(), &dbs.UserNewParams{
 		Username: req.Email,
-		Password: string(passwordCrypted),
+		Password: passwordCrypted,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
@@ -81,30 +246,47 @@ func (rcv *AuthService) Signup(req *exchange.AuthSignupReq) (*dbs.UserNewRow, er
 		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
 	}
 
-	// send an verification email
-	// TODO: should be implemented
-
 	// commit transaction
 	if err := trx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
 	}
+
+	// send an verification email, the user may ask to resend it
+	if err := rcv.sendVerification(ctx, user.ID, req.Email); err != nil {
+		slog.Error("verification email", "user_id", user.ID, "error", err)
+	}
 	return user, nil
 }
 
-func (rcv *AuthService) Signin(req *exchange.AuthSigninReq) (*exchange.AuthSigninRes, error) {
+func (rcv *AuthService) Signin(req *exchange.AuthSigninReq, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
 	ctx := context.Background()
 
+	// locked out usernames and client addresses are rejected before the password check
+	locks := rcv.signinLocks(req.Username, device.IpAddress)
+	if err := rcv.signinLocked(ctx, locks); err != nil {
+		return nil, err
+	}
+
 	// check user password
 	user, err := rcv.queries.AuthSelectUserCredentials(ctx, req.Username)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+			return nil, rcv.signinFailed(ctx, locks, fmt.Errorf("%w: %v", common.ErrDBNotFound, err))
 		} else {
 			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
 		}
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, fmt.Errorf("%w: %v", common.ErrAuthInvalidPassword, err)
+	if err := rcv.passwordHasher.Compare(user.Password, req.Password); err != nil {
+		return nil, rcv.signinFailed(ctx, locks, fmt.Errorf("%w: %v", common.ErrAuthInvalidPassword, err))
+	}
+	if err := unlockSignin(rcv.redis, req.Username); err != nil {
+		slog.Error("signin unlock", "user_id", user.ID, "error", err)
+	}
+	// upgrade the legacy or weaker hash while the plain password is at hand
+	if rcv.passwordHasher.NeedsRehash(user.Password) {
+		if err := rcv.rehashPassword(ctx, user.ID, req.Password); err != nil {
+			slog.Error("signin rehash", "user_id", user.ID, "error", err)
+		}
 	}
 
 	// check for blocked or checked statuses
@@ -114,59 +296,1579 @@ func (rcv *AuthService) Signin(req *exchange.AuthSigninReq) (*exchange.AuthSigni
 	if !user.IsChecked {
 		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserNotChecked, errors.New("email check required"))
 	}
+	return rcv.signin(ctx, user.ID, device)
+}
 
-	// generate tokens
-	accessToken, refreshToken, err := rcv.jwtProvider.GenerateTokens(user.ID)
+func (rcv *AuthService) Signout(accessToken string, req *exchange.AuthSignoutReq) (*common.Message, error) {
+	access, err := rcv.jwtProvider.ValidateToken(accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+		return nil, err
+	}
+	if access.Type != provider.TokenTypeAccess {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, access.Type)
+	}
+	refresh, err := rcv.jwtProvider.ValidateToken(req.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	if refresh.Type != provider.TokenTypeRefresh {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, refresh.Type)
+	}
+	if refresh.UserID() != access.UserID() {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, errors.New("token owner mismatch"))
 	}
 
-	// update user visited_at
-	updated, err := rcv.queries.AuthUpdateVisitedAt(ctx, user.ID)
+	// revoke both tokens until their own expiration
+	if err := rcv.jwtProvider.InvalidateToken(accessToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	if err := rcv.jwtProvider.InvalidateToken(req.Refresh); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	if err := rcv.queries.UserSessionDeleteByFamilyID(context.Background(), refresh.FamilyID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	return &common.Message{Message: "signed out"}, nil
+}
+
+func (rcv *AuthService) SignoutEverywhere(accessToken string) (*common.Message, error) {
+	access, err := rcv.jwtProvider.ValidateToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if access.Type != provider.TokenTypeAccess {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, access.Type)
+	}
+	if err := rcv.jwtProvider.InvalidateUserTokens(access.UserID()); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	if err := rcv.jwtProvider.InvalidateToken(accessToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	if err := rcv.queries.UserSessionDeleteByUserID(context.Background(), access.UserID()); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	return &common.Message{Message: "signed out everywhere"}, nil
+}
+
+func (rcv *AuthService) Refresh(req *exchange.AuthTokenRefreshReq) (*exchange.AuthTokens, error) {
+	ctx := context.Background()
+
+	claims, err := rcv.jwtProvider.ValidateToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != provider.TokenTypeRefresh {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	// tokens of OAuth clients are refreshed by the token endpoint only
+	if claims.ClientID != "" {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, "client token")
+	}
+
+	// user could be blocked since the token was issued
+	user, err := rcv.queries.UserSelectByID(ctx, claims.UserID())
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if user.IsBlocked {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserBlocked, errors.New("block status detected"))
+	}
+
+	// rotate tokens, the presented refresh token becomes unusable
+	accessToken, refreshToken, err := rcv.jwtProvider.RefreshTokens(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// the session lives on as long as its family is refreshed
+	err = rcv.queries.UserSessionUpdateByFamilyID(ctx, &dbs.UserSessionUpdateByFamilyIDParams{
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(rcv.refreshExpiration), Valid: true},
+		FamilyID:  claims.FamilyID,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
 	}
+	return &exchange.AuthTokens{Access: accessToken, Refresh: refreshToken}, nil
+}
 
-	// store refresh token in redis
-	if err := rcv.jwtProvider.StoreToken(refreshToken); err != nil {
-		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+func (rcv *AuthService) Jwks() *provider.Jwks {
+	return rcv.jwtProvider.Jwks()
+}
+
+func (rcv *AuthService) TwoFAEnroll(userID string) (*exchange.Auth2FAEnrollRes, error) {
+	ctx := context.Background()
+
+	user, err := rcv.queries.UserSelectByID(ctx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	profile, err := rcv.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAAlreadyEnabled, errors.New("disable it first"))
 	}
 
-	// response about logged in user
-	res := &exchange.AuthSigninRes{
-		User: &exchange.AuthUser{
-			ID:        updated.ID,
-			Username:  updated.Username,
-			CheckedAt: updated.CheckedAt,
-			VisitedAt: updated.VisitedAt,
-			CreatedAt: updated.CreatedAt,
-		},
-		Tokens: &exchange.AuthTokens{
-			Access:  accessToken,
-			Refresh: refreshToken,
-		},
+	// secret is stored disabled until the first code confirms it
+	secret, url, err := rcv.twoFAProvider.GenerateSecretKey(user.Username)
+	if err != nil {
+		return nil, err
 	}
-	return res, nil
+	qrcode, err := rcv.twoFAProvider.GenerateQRCode(url)
+	if err != nil {
+		return nil, err
+	}
+
+	// begin new transaction
+	trx, err := rcv.pgxProvider.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	defer trx.Rollback(ctx)
+	qtx := rcv.queries.WithTx(trx)
+
+	_, err = qtx.ProfileUpdate2FAById(ctx, &dbs.ProfileUpdate2FAByIdParams{
+		ID: profile.ID, Enable2fa: false, Secret2fa: pgtype.Text{String: secret, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	codes, err := rcv.storeRecoveryCodes(ctx, qtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit transaction
+	if err := trx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	return &exchange.Auth2FAEnrollRes{
+		Secret:        secret,
+		Url:           url,
+		QRCode:        base64.StdEncoding.EncodeToString(qrcode),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (rcv *AuthService) TwoFAConfirm(userID string, req *exchange.Auth2FACodeReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	profile, err := rcv.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAAlreadyEnabled, errors.New("already confirmed"))
+	}
+	if !profile.Secret2fa.Valid {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("enrollment required"))
+	}
+	if !rcv.twoFAProvider.VerifyCode(profile.Secret2fa.String, req.Code) {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAInvalidCode, errors.New("code mismatch"))
+	}
+	_, err = rcv.queries.ProfileUpdate2FAById(ctx, &dbs.ProfileUpdate2FAByIdParams{
+		ID: profile.ID, Enable2fa: true, Secret2fa: profile.Secret2fa,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	return &common.Message{Message: "2fa enabled"}, nil
+}
+
+func (rcv *AuthService) TwoFADisable(userID string, req *exchange.Auth2FACodeReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	profile, err := rcv.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("nothing to disable"))
+	}
+	if !rcv.twoFAProvider.VerifyCode(profile.Secret2fa.String, req.Code) {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAInvalidCode, errors.New("code mismatch"))
+	}
+
+	// begin new transaction
+	trx, err := rcv.pgxProvider.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	defer trx.Rollback(ctx)
+	qtx := rcv.queries.WithTx(trx)
+
+	_, err = qtx.ProfileUpdate2FAById(ctx, &dbs.ProfileUpdate2FAByIdParams{
+		ID: profile.ID, Enable2fa: false, Secret2fa: pgtype.Text{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	if err := qtx.RecoveryCodeDeleteByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+
+	// commit transaction
+	if err := trx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	return &common.Message{Message: "2fa disabled"}, nil
+}
+
+// TwoFARecoveryCodes replaces the recovery codes, the previous ones stop working
+func (rcv *AuthService) TwoFARecoveryCodes(userID string, req *exchange.Auth2FACodeReq) (*exchange.Auth2FARecoveryCodesRes, error) {
+	ctx := context.Background()
+
+	profile, err := rcv.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("enrollment required"))
+	}
+	if !rcv.twoFAProvider.VerifyCode(profile.Secret2fa.String, req.Code) {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAInvalidCode, errors.New("code mismatch"))
+	}
+
+	// begin new transaction
+	trx, err := rcv.pgxProvider.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	defer trx.Rollback(ctx)
+
+	codes, err := rcv.storeRecoveryCodes(ctx, rcv.queries.WithTx(trx), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit transaction
+	if err := trx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	return &exchange.Auth2FARecoveryCodesRes{RecoveryCodes: codes}, nil
+}
+
+// TwoFAVerify exchanges the sign-in challenge and a valid code for the tokens
+func (rcv *AuthService) TwoFAVerify(req *exchange.Auth2FAVerifyReq, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	ctx := context.Background()
+
+	claims, err := rcv.jwtProvider.ValidateToken(req.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != provider.TokenTypeChallenge {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	profile, err := rcv.profile(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("challenge outdated"))
+	}
+	if req.RecoveryCode != "" {
+		_, err := rcv.queries.RecoveryCodeUseByUserID(ctx, &dbs.RecoveryCodeUseByUserIDParams{
+			UserID: claims.UserID(), CodeHash: rcv.twoFAProvider.HashRecoveryCode(req.RecoveryCode),
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, rcv.challengeFailed(ctx, claims, req.Challenge)
+			} else {
+				return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+			}
+		}
+		rcv.notifyRecoveryCodeUsed(ctx, claims.UserID())
+	} else if !rcv.twoFAProvider.VerifyCode(profile.Secret2fa.String, req.Code) {
+		return nil, rcv.challengeFailed(ctx, claims, req.Challenge)
+	}
+
+	// challenge is single use
+	if err := rcv.jwtProvider.InvalidateToken(req.Challenge); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	return rcv.issueTokens(ctx, claims.UserID(), device)
+}
+
+// Verify consumes the emailed token and marks the user as checked
+func (rcv *AuthService) Verify(req *exchange.AuthVerifyReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	claims, err := rcv.jwtProvider.ValidateToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != provider.TokenTypeVerify {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	_, err = rcv.queries.UserUpdateIsCheckedByID(ctx, &dbs.UserUpdateIsCheckedByIDParams{
+		IsChecked: true, ID: claims.UserID(),
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+		}
+	}
+	invalidatePrincipal(rcv.redis, claims.UserID())
+
+	// verification token is single use
+	if err := rcv.jwtProvider.InvalidateToken(req.Token); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	return &common.Message{Message: "email verified"}, nil
 }
 
-func (rcv *AuthService) Signout() (bool, error) {
-	return true, fmt.Errorf("%w: %v", common.ErrNotImplemented, errors.New("Auth.Signout()"))
+// VerifyResend sends a new verification email. The response does not tell
+// whether the address is registered, throttling is applied per address.
+func (rcv *AuthService) VerifyResend(req *exchange.AuthVerifyResendReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	ok, err := rcv.redis.SetNX(ctx, keyVerifyResend+strings.ToLower(req.Email), 1, rcv.verifyResendInterval).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthTooManyRequests, errors.New("verification email already sent"))
+	}
+	res := &common.Message{Message: "verification email sent"}
+
+	user, err := rcv.queries.AuthSelectUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return res, nil
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if user.IsChecked || user.IsBlocked {
+		return res, nil
+	}
+	if err := rcv.sendVerification(ctx, user.ID, req.Email); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
-// --------------------------------------------------------------------------------------
-// func (rcv *AuthService) LoginWith2FA(userEmail, password, code string) (bool, error) {
-// 	// Заглушка проверки пароля (заменить на реальную логику)
-// 	if password != "correct_password" {
-// 		return false, errors.New("invalid credentials")
-// 	}
+// PasswordReset emails a single-use reset link. The response does not tell
+// whether the address is registered, throttling is applied per address.
+func (rcv *AuthService) PasswordReset(req *exchange.AuthPasswordResetReq) (*common.Message, error) {
+	ctx := context.Background()
 
-// 	// Проверяем, включен ли 2FA (секрет должен быть в БД)
-// 	secret := "user_saved_secret" // Достать из БД по userEmail
-// 	if secret != "" {
-// 		if !rcv.twoFA.VerifyCode(secret, code) {
-// 			return false, errors.New("invalid 2FA code")
-// 		}
-// 	}
+	ok, err := rcv.redis.SetNX(ctx, keyPasswordReset+strings.ToLower(req.Email), 1, rcv.resetInterval).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthTooManyRequests, errors.New("password reset email already sent"))
+	}
+	res := &common.Message{Message: "password reset email sent"}
 
-// 	return true, nil
-// }
+	user, err := rcv.queries.AuthSelectUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return res, nil
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if user.IsBlocked {
+		return res, nil
+	}
+
+	// only the latest requested token stays valid
+	token, err := common.RandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	if err := rcv.queries.PasswordResetDeleteByUserID(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	_, err = rcv.queries.PasswordResetNew(ctx, &dbs.PasswordResetNewParams{
+		UserID:    user.ID,
+		TokenHash: common.HashToken(token),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(rcv.resetExpiration), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	link, err := rcv.link(rcv.resetUrl, token)
+	if err != nil {
+		return nil, err
+	}
+	err = rcv.mailerProvider.Send(&provider.Mail{
+		To:      []string{req.Email},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Follow the link to set a new password:\n\n%s\n\nThe link expires in %s. "+
+				"If you did not ask for a password reset, ignore this email.", link, rcv.resetExpiration,
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PasswordResetConfirm sets the new password and revokes every session of the user
+func (rcv *AuthService) PasswordResetConfirm(req *exchange.AuthPasswordResetConfirmReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	passwordCrypted, err := rcv.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrPasswordHash, err)
+	}
+
+	// begin new transaction
+	trx, err := rcv.pgxProvider.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	defer trx.Rollback(ctx)
+	qtx := rcv.queries.WithTx(trx)
+
+	reset, err := qtx.PasswordResetUseByTokenHash(ctx, common.HashToken(req.Token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, errors.New("unknown, used or expired reset token"))
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+		}
+	}
+	// a rejected password leaves the reset token unused
+	if err := rcv.validatePassword(ctx, reset.UserID, req.Password); err != nil {
+		return nil, err
+	}
+	_, err = qtx.AuthUpdatePasswordByID(ctx, &dbs.AuthUpdatePasswordByIDParams{
+		ID: reset.UserID, Password: passwordCrypted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	if err := qtx.UserSessionDeleteByUserID(ctx, reset.UserID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+
+	// commit transaction
+	if err := trx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	if err := rcv.jwtProvider.InvalidateUserTokens(reset.UserID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	return &common.Message{Message: "password changed"}, nil
+}
+
+// PasswordChange replaces the password of the signed-in user, the current session survives
+func (rcv *AuthService) PasswordChange(claims *provider.Claims, req *exchange.AuthPasswordChangeReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	user, err := rcv.queries.UserSelectByID(ctx, claims.UserID())
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	credentials, err := rcv.queries.AuthSelectUserCredentials(ctx, user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err := rcv.passwordHasher.Compare(credentials.Password, req.Current); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthInvalidPassword, err)
+	}
+	if err := rcv.validatePassword(ctx, user.ID, req.Password); err != nil {
+		return nil, err
+	}
+	passwordCrypted, err := rcv.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrPasswordHash, err)
+	}
+	_, err = rcv.queries.AuthUpdatePasswordByID(ctx, &dbs.AuthUpdatePasswordByIDParams{
+		ID: user.ID, Password: passwordCrypted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	if req.RevokeSessions {
+		if err := rcv.jwtProvider.InvalidateUserTokensExcept(user.ID, claims.FamilyID); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+		}
+		err = rcv.queries.UserSessionDeleteByUserIDExcept(ctx, &dbs.UserSessionDeleteByUserIDExceptParams{
+			UserID: user.ID, FamilyID: claims.FamilyID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	return &common.Message{Message: "password changed"}, nil
+}
+
+// MagicLink emails a single-use sign-in link. Like the password reset the
+// response does not tell whether the address is registered or may use the link.
+func (rcv *AuthService) MagicLink(req *exchange.AuthMagicLinkReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	if !rcv.magicLinkEnabled {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthForbidden, errors.New("magic link sign-in disabled"))
+	}
+	ok, err := rcv.redis.SetNX(ctx, keyMagicLink+strings.ToLower(req.Email), 1, rcv.magicLinkInterval).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthTooManyRequests, errors.New("magic link email already sent"))
+	}
+	res := &common.Message{Message: "magic link email sent"}
+
+	user, err := rcv.queries.AuthSelectUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return res, nil
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if user.IsBlocked || !user.IsChecked {
+		return res, nil
+	}
+	profile, err := rcv.queries.ProfileSelectByUserID(ctx, user.ID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == nil && !profile.EnableMagicLink {
+		return res, nil
+	}
+
+	// only the latest requested token stays valid
+	token, err := common.RandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	if err := rcv.queries.MagicLinkDeleteByUserID(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	_, err = rcv.queries.MagicLinkNew(ctx, &dbs.MagicLinkNewParams{
+		UserID:    user.ID,
+		TokenHash: common.HashToken(token),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(rcv.magicLinkExpiration), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	link, err := rcv.link(rcv.magicLinkUrl, token)
+	if err != nil {
+		return nil, err
+	}
+	err = rcv.mailerProvider.Send(&provider.Mail{
+		To:      []string{req.Email},
+		Subject: "Sign in",
+		Body: fmt.Sprintf(
+			"Follow the link to sign in:\n\n%s\n\nThe link expires in %s and works once. "+
+				"If you did not ask to sign in, ignore this email.", link, rcv.magicLinkExpiration,
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// MagicLinkConsume redeems the link for the tokens, the sign-in checks of the
+// password flow apply and the second factor is still challenged
+func (rcv *AuthService) MagicLinkConsume(req *exchange.AuthMagicLinkConsumeReq, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	ctx := context.Background()
+
+	if !rcv.magicLinkEnabled {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthForbidden, errors.New("magic link sign-in disabled"))
+	}
+	magicLink, err := rcv.queries.MagicLinkUseByTokenHash(ctx, common.HashToken(req.Token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, errors.New("unknown, used or expired magic link token"))
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+		}
+	}
+	user, err := rcv.queries.UserSelectByID(ctx, magicLink.UserID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+
+	// check for blocked or checked statuses
+	if user.IsBlocked {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserBlocked, errors.New("block status detected"))
+	}
+	if !user.IsChecked {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserNotChecked, errors.New("email check required"))
+	}
+
+	// the user may have opted out after the link was sent
+	profile, err := rcv.queries.ProfileSelectByUserID(ctx, user.ID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == nil && !profile.EnableMagicLink {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthForbidden, errors.New("magic link sign-in disabled by the user"))
+	}
+	return rcv.signin(ctx, user.ID, device)
+}
+
+// MagicLinkToggle allows or disallows the magic link sign-in for the user,
+// disabling drops the pending link
+func (rcv *AuthService) MagicLinkToggle(userID string, req *exchange.AuthMagicLinkToggleReq) (*common.Message, error) {
+	ctx := context.Background()
+
+	_, err := rcv.queries.ProfileUpdateMagicLinkByUserID(ctx, &dbs.ProfileUpdateMagicLinkByUserIDParams{
+		EnableMagicLink: *req.Enabled, UserID: userID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+		}
+	}
+	if !*req.Enabled {
+		if err := rcv.queries.MagicLinkDeleteByUserID(ctx, userID); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+		return &common.Message{Message: "magic link sign-in disabled"}, nil
+	}
+	return &common.Message{Message: "magic link sign-in enabled"}, nil
+}
+
+// OidcProviders lists the configured identity providers the sign-in page offers
+func (rcv *AuthService) OidcProviders() []*exchange.AuthOidcProvider {
+	res := []*exchange.AuthOidcProvider{}
+	for _, config := range rcv.oidcProvider.Providers() {
+		res = append(res, &exchange.AuthOidcProvider{Name: config.Name, DisplayName: config.DisplayName})
+	}
+	return res
+}
+
+// OidcStart begins the federated sign-in, or links the identity to the user when
+// the user is given. The state, nonce and PKCE verifier are kept for the callback.
+func (rcv *AuthService) OidcStart(name, userID string) (*exchange.AuthOidcStartRes, error) {
+	ctx := context.Background()
+
+	tokens := make([]string, 3)
+	for i := range tokens {
+		token, err := common.RandomToken(oidcTokenSize)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+		}
+		tokens[i] = token
+	}
+	state, nonce, verifier := tokens[0], tokens[1], tokens[2]
+
+	redirectTo, err := rcv.oidcProvider.AuthCodeUrl(name, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(&oidcState{Provider: name, Nonce: nonce, Verifier: verifier, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	if err := rcv.redis.Set(ctx, keyOidcState+common.HashToken(state), data, rcv.oidcStateExpiration).Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return &exchange.AuthOidcStartRes{RedirectTo: redirectTo}, nil
+}
+
+// OidcSignin completes the federated sign-in, unknown identities are provisioned
+// just in time. The second factor of the local user still applies.
+func (rcv *AuthService) OidcSignin(name string, req *exchange.AuthOidcCallbackReq, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	ctx := context.Background()
+
+	identity, config, err := rcv.oidcIdentity(ctx, name, "", req)
+	if err != nil {
+		return nil, err
+	}
+	var userID string
+
+	linked, err := rcv.queries.UserIdentitySelectByProviderSubject(ctx, &dbs.UserIdentitySelectByProviderSubjectParams{
+		Provider: identity.Provider, Subject: identity.Subject,
+	})
+	switch {
+	case err == nil:
+		userID = linked.UserID
+		err = rcv.queries.UserIdentityUpdateLastUsedAtByID(ctx, &dbs.UserIdentityUpdateLastUsedAtByIDParams{
+			ID: linked.ID, Email: identity.Email,
+		})
+		if err != nil {
+			slog.Error("identity last use", "id", linked.ID, "error", err)
+		}
+	case err == pgx.ErrNoRows:
+		if userID, err = rcv.oidcProvision(ctx, config, identity); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+
+	user, err := rcv.queries.UserSelectByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if user.IsBlocked {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserBlocked, errors.New("block status detected"))
+	}
+	return rcv.signin(ctx, user.ID, device)
+}
+
+// OidcLink completes linking the identity to the signed-in user
+func (rcv *AuthService) OidcLink(name, userID string, req *exchange.AuthOidcCallbackReq) (*dbs.UserIdentityNewRow, error) {
+	ctx := context.Background()
+
+	identity, _, err := rcv.oidcIdentity(ctx, name, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := rcv.queries.UserIdentitySelectByProviderSubject(ctx, &dbs.UserIdentitySelectByProviderSubjectParams{
+		Provider: identity.Provider, Subject: identity.Subject,
+	})
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == nil {
+		if linked.UserID != userID {
+			return nil, fmt.Errorf("%w: %v", common.ErrAuthForbidden, "identity is linked to another user")
+		}
+		return (*dbs.UserIdentityNewRow)(linked), nil
+	}
+	res, err := rcv.queries.UserIdentityNew(ctx, &dbs.UserIdentityNewParams{
+		UserID: userID, Provider: identity.Provider, Subject: identity.Subject, Email: identity.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return res, nil
+}
+
+func (rcv *AuthService) IdentitySelect(userID string) ([]*dbs.UserIdentitySelectByUserIDRow, error) {
+	res, err := rcv.queries.UserIdentitySelectByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.UserIdentitySelectByUserIDRow{}
+	}
+	return res, nil
+}
+
+// IdentityUnlink removes the identity, the last identity of a user without a
+// password cannot be removed as the user could not sign in anymore
+func (rcv *AuthService) IdentityUnlink(userID, id string) error {
+	ctx := context.Background()
+
+	user, err := rcv.queries.UserSelectByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	credentials, err := rcv.queries.AuthSelectUserCredentials(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	count, err := rcv.queries.UserIdentityCountByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordCount, err)
+	}
+	if credentials.Password == unusablePassword && count <= 1 {
+		return fmt.Errorf("%w: %v", common.ErrAuthForbidden, "set a password before removing the last identity")
+	}
+	_, err = rcv.queries.UserIdentityDeleteByUserIDID(ctx, &dbs.UserIdentityDeleteByUserIDIDParams{
+		UserID: userID, ID: id,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	return nil
+}
+
+// WebAuthnRegisterBegin starts the registration of a passkey for the signed-in user
+func (rcv *AuthService) WebAuthnRegisterBegin(userID string) (*exchange.AuthWebAuthnBeginRes, error) {
+	ctx := context.Background()
+
+	user, _, err := rcv.webauthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	options, session, err := rcv.webauthn.BeginRegistration(user)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	return rcv.webauthnBegin(ctx, options, &webauthnState{
+		Ceremony: webauthnCeremonyRegister, UserID: userID, Session: session,
+	})
+}
+
+// WebAuthnRegisterFinish verifies the attestation and stores the passkey
+func (rcv *AuthService) WebAuthnRegisterFinish(userID string, req *exchange.AuthWebAuthnRegisterFinishReq) (*dbs.WebauthnCredentialNewRow, error) {
+	ctx := context.Background()
+
+	state, err := rcv.webauthnFinish(ctx, req.State, webauthnCeremonyRegister)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID != userID {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCeremony, "started by another user")
+	}
+	user, _, err := rcv.webauthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := rcv.webauthn.FinishRegistration(user, state.Session, req.Credential)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+	name := req.Name
+	if name == "" {
+		name = "passkey"
+	}
+	row, err := rcv.queries.WebauthnCredentialNew(ctx, &dbs.WebauthnCredentialNewParams{
+		UserID: userID, Name: name, CredentialID: credential.ID, Credential: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return row, nil
+}
+
+// WebAuthnLoginBegin starts the passwordless sign-in, the authenticator picks
+// the passkey so no username is needed
+func (rcv *AuthService) WebAuthnLoginBegin() (*exchange.AuthWebAuthnBeginRes, error) {
+	ctx := context.Background()
+
+	options, session, err := rcv.webauthn.BeginPasskeyLogin()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	return rcv.webauthnBegin(ctx, options, &webauthnState{
+		Ceremony: webauthnCeremonyLogin, Session: session,
+	})
+}
+
+// WebAuthnLoginFinish verifies the assertion and issues the tokens, a verified
+// passkey stands for both factors so no challenge follows
+func (rcv *AuthService) WebAuthnLoginFinish(req *exchange.AuthWebAuthnFinishReq, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	ctx := context.Background()
+
+	state, err := rcv.webauthnFinish(ctx, req.State, webauthnCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+	var found *dbs.UserSelectByIDRow
+	user, credential, err := rcv.webauthn.FinishPasskeyLogin(func(userID string) (*provider.WebAuthnUser, error) {
+		user, row, err := rcv.webauthnUser(ctx, userID)
+		found = row
+		return user, err
+	}, state.Session, req.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	// check for blocked or checked statuses
+	if found.IsBlocked {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserBlocked, errors.New("block status detected"))
+	}
+	if !found.IsChecked {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthUserNotChecked, errors.New("email check required"))
+	}
+	if err := rcv.webauthnUsed(ctx, user.ID, credential); err != nil {
+		return nil, err
+	}
+	return rcv.issueTokens(ctx, user.ID, device)
+}
+
+// WebAuthn2FABegin starts the passkey assertion answering the sign-in challenge
+func (rcv *AuthService) WebAuthn2FABegin(req *exchange.AuthWebAuthn2FABeginReq) (*exchange.AuthWebAuthnBeginRes, error) {
+	ctx := context.Background()
+
+	claims, err := rcv.jwtProvider.ValidateToken(req.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != provider.TokenTypeChallenge {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	profile, err := rcv.profile(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("challenge outdated"))
+	}
+	user, _, err := rcv.webauthnUser(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if len(user.Credentials) == 0 {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("no passkey registered"))
+	}
+	options, session, err := rcv.webauthn.BeginLogin(user)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	return rcv.webauthnBegin(ctx, options, &webauthnState{
+		Ceremony: webauthnCeremony2FA, UserID: user.ID, ChallengeID: claims.ID, Session: session,
+	})
+}
+
+// WebAuthn2FAFinish verifies the assertion and completes the sign-in challenge
+func (rcv *AuthService) WebAuthn2FAFinish(req *exchange.AuthWebAuthn2FAFinishReq, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	ctx := context.Background()
+
+	claims, err := rcv.jwtProvider.ValidateToken(req.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != provider.TokenTypeChallenge {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	profile, err := rcv.profile(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Enable2fa {
+		return nil, fmt.Errorf("%w: %v", common.Err2FANotEnabled, errors.New("challenge outdated"))
+	}
+	state, err := rcv.webauthnFinish(ctx, req.State, webauthnCeremony2FA)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID != claims.UserID() || state.ChallengeID != claims.ID {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCeremony, "started for another challenge")
+	}
+	user, _, err := rcv.webauthnUser(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	credential, err := rcv.webauthn.FinishLogin(user, state.Session, req.Credential)
+	if err != nil {
+		return nil, rcv.challengeFailed(ctx, claims, req.Challenge)
+	}
+	if err := rcv.webauthnUsed(ctx, user.ID, credential); err != nil {
+		return nil, err
+	}
+
+	// challenge is single use
+	if err := rcv.jwtProvider.InvalidateToken(req.Challenge); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	return rcv.issueTokens(ctx, claims.UserID(), device)
+}
+
+func (rcv *AuthService) WebAuthnCredentialSelect(userID string) ([]*dbs.WebauthnCredentialSelectByUserIDRow, error) {
+	ctx := context.Background()
+
+	credentials, err := rcv.queries.WebauthnCredentialSelectByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	return credentials, nil
+}
+
+func (rcv *AuthService) WebAuthnCredentialDelete(userID, id string) error {
+	ctx := context.Background()
+
+	_, err := rcv.queries.WebauthnCredentialDeleteByUserIDID(ctx, &dbs.WebauthnCredentialDeleteByUserIDIDParams{
+		UserID: userID, ID: id,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	return nil
+}
+
+func (rcv *AuthService) SessionSelect(userID string) ([]*dbs.UserSessionSelectByUserIDRow, error) {
+	ctx := context.Background()
+
+	sessions, err := rcv.queries.UserSessionSelectByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	return sessions, nil
+}
+
+// SessionRevoke signs the device out, the tokens of the session family stop
+// validating at once
+func (rcv *AuthService) SessionRevoke(userID, id string) error {
+	ctx := context.Background()
+
+	familyID, err := rcv.queries.UserSessionDeleteByUserIDID(ctx, &dbs.UserSessionDeleteByUserIDIDParams{
+		UserID: userID, ID: id,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	if err := rcv.jwtProvider.InvalidateFamily(userID, familyID); err != nil {
+		return fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	return nil
+}
+
+// Me collects the identity of the signed-in user, the 2fa secret is never selected,
+// roles and permissions are the ones the auth middleware resolved for the principal
+func (rcv *AuthService) Me(principal *common.Principal) (*exchange.AuthMeRes, error) {
+	ctx, userID := context.Background(), principal.UserID
+
+	me, err := rcv.queries.AuthSelectMe(ctx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	contacts, err := rcv.queries.ContactSelectByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	res := &exchange.AuthMeRes{
+		ID:          me.UserID,
+		Username:    me.UserUsername,
+		IsBlocked:   me.UserIsBlocked,
+		IsChecked:   me.UserIsChecked,
+		Contacts:    []*exchange.AuthMeContact{},
+		Roles:       principal.Roles,
+		Permissions: principal.Permissions,
+	}
+	// the view carries the 2fa secret, the profile is copied field by field to leave it out
+	if me.ProfileID.Valid {
+		res.Profile = &exchange.AuthMeProfile{
+			ID:        me.ProfileID.String,
+			Firstname: me.ProfileFirstname.String,
+			Lastname:  me.ProfileLastname.String,
+			Gender:    me.ProfileGender.String,
+			Birthday:  me.ProfileBirthday,
+			AvatarUrl: me.ProfileAvatarUrl.String,
+			Enable2fa: me.ProfileEnable2fa.Bool,
+		}
+	}
+	for _, contact := range contacts {
+		res.Contacts = append(res.Contacts, &exchange.AuthMeContact{
+			ID: contact.ID, Class: contact.Class, Content: contact.Content,
+		})
+	}
+	return res, nil
+}
+
+func (rcv *AuthService) sendVerification(ctx context.Context, userID, email string) error {
+	token, err := rcv.jwtProvider.GenerateToken(userID, provider.TokenTypeVerify, rcv.verifyExpiration)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	link, err := rcv.link(rcv.verifyUrl, token)
+	if err != nil {
+		return err
+	}
+	return rcv.mailerProvider.Send(&provider.Mail{
+		To:      []string{email},
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Follow the link to verify your email address:\n\n%s\n\nThe link expires in %s.", link, rcv.verifyExpiration,
+		),
+	})
+}
+
+// link appends the token to the configured url as query parameter
+func (rcv *AuthService) link(base, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// signin issues the tokens, or the challenge when the user enabled the second factor
+func (rcv *AuthService) signin(ctx context.Context, userID string, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	// second factor required, the tokens are issued by the challenge verification
+	profile, err := rcv.queries.ProfileSelectByUserID(ctx, userID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == nil && profile.Enable2fa {
+		// the registered passkeys answer the challenge as well as the totp code
+		methods := []string{ChallengeMethodTOTP}
+		passkeys, err := rcv.queries.WebauthnCredentialCountByUserID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordCount, err)
+		}
+		if passkeys > 0 {
+			methods = append(methods, ChallengeMethodWebAuthn)
+		}
+		challenge, err := rcv.jwtProvider.GenerateToken(userID, provider.TokenTypeChallenge, rcv.challengeExpiration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+		}
+		return &exchange.AuthSigninRes{
+			Challenge: &exchange.AuthChallenge{
+				Token: challenge, ExpiresIn: int(rcv.challengeExpiration.Seconds()), Methods: methods,
+			},
+		}, nil
+	}
+	return rcv.issueTokens(ctx, userID, device)
+}
+
+// oidcIdentity redeems the state and the code for the verified identity, the
+// state is single use and bound to the provider and the user it was started for
+func (rcv *AuthService) oidcIdentity(ctx context.Context, name, userID string, req *exchange.AuthOidcCallbackReq) (*provider.OidcIdentity, *provider.OidcProviderConfig, error) {
+	config, err := rcv.oidcProvider.Provider(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := rcv.redis.GetDel(ctx, keyOidcState+common.HashToken(req.State)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, "invalid or expired state")
+		}
+		return nil, nil, err
+	}
+	state := &oidcState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, nil, err
+	}
+	if state.Provider != name || state.UserID != userID {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, "state mismatch")
+	}
+	identity, err := rcv.oidcProvider.Exchange(name, req.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	identity.EmailVerified = identity.EmailVerified || config.TrustEmail
+
+	if len(config.AllowedDomains) > 0 {
+		_, domain, _ := strings.Cut(identity.Email, "@")
+		if !identity.EmailVerified || !common.Contains(config.AllowedDomains, domain) {
+			return nil, nil, fmt.Errorf("%w: %v", common.ErrAuthForbidden, "email domain is not allowed")
+		}
+	}
+	return identity, config, nil
+}
+
+// oidcProvision links the first sign-in of the identity to a user, a new user is
+// created with the email, profile and identity unless the email is already taken
+func (rcv *AuthService) oidcProvision(ctx context.Context, config *provider.OidcProviderConfig, identity *provider.OidcIdentity) (string, error) {
+	if identity.Email == "" || !identity.EmailVerified {
+		return "", fmt.Errorf("%w: %v", common.ErrAuthForbidden, "verified email required")
+	}
+	existing, err := rcv.queries.AuthSelectUserByEmail(ctx, identity.Email)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == nil {
+		if !config.LinkByEmail {
+			return "", fmt.Errorf("%w: %v", common.ErrAuthForbidden, "email is taken, sign in and link the identity")
+		}
+		_, err := rcv.queries.UserIdentityNew(ctx, &dbs.UserIdentityNewParams{
+			UserID: existing.ID, Provider: identity.Provider, Subject: identity.Subject, Email: identity.Email,
+		})
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+		}
+		return existing.ID, nil
+	}
+
+	// begin new transaction
+	trx, err := rcv.pgxProvider.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	defer func() {
+		if err != nil {
+			trx.Rollback(ctx)
+		}
+	}()
+	qtx := rcv.queries.WithTx(trx)
+
+	// the provider verified the email, there is no password to sign in with
+	user, err := qtx.UserIdentityUserNew(ctx, identity.Email)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	_, err = qtx.ContactNew(ctx, &dbs.ContactNewParams{
+		UserID: user.ID, Class: ContactClassEmail, Content: identity.Email,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	_, err = qtx.ProfileNew(ctx, &dbs.ProfileNewParams{
+		UserID: user.ID, Firstname: identity.Firstname, Lastname: identity.Lastname,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	_, err = qtx.UserIdentityNew(ctx, &dbs.UserIdentityNewParams{
+		UserID: user.ID, Provider: identity.Provider, Subject: identity.Subject, Email: identity.Email,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+
+	// commit transaction
+	if err = trx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBTrxError, err)
+	}
+	return user.ID, nil
+}
+
+// webauthnUser loads the user with the registered passkeys
+func (rcv *AuthService) webauthnUser(ctx context.Context, userID string) (*provider.WebAuthnUser, *dbs.UserSelectByIDRow, error) {
+	row, err := rcv.queries.UserSelectByID(ctx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	data, err := rcv.queries.WebauthnCredentialSelectCredentialByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	user := &provider.WebAuthnUser{ID: row.ID, Name: row.Username, Credentials: []webauthn.Credential{}}
+	for _, item := range data {
+		credential := webauthn.Credential{}
+		if err := json.Unmarshal(item, &credential); err != nil {
+			return nil, nil, err
+		}
+		user.Credentials = append(user.Credentials, credential)
+	}
+	return user, row, nil
+}
+
+// webauthnBegin keeps the ceremony in redis under the hash of the returned state
+func (rcv *AuthService) webauthnBegin(ctx context.Context, options any, state *webauthnState) (*exchange.AuthWebAuthnBeginRes, error) {
+	token, err := common.RandomToken(oidcTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := rcv.redis.Set(ctx, keyWebAuthnState+common.HashToken(token), data, rcv.webauthnExpiration).Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return &exchange.AuthWebAuthnBeginRes{State: token, Options: options}, nil
+}
+
+// webauthnFinish redeems the state, a ceremony is finished at most once
+func (rcv *AuthService) webauthnFinish(ctx context.Context, token, ceremony string) (*webauthnState, error) {
+	data, err := rcv.redis.GetDel(ctx, keyWebAuthnState+common.HashToken(token)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCeremony, err)
+		}
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	state := &webauthnState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Ceremony != ceremony || state.Session == nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCeremony, "ceremony mismatch")
+	}
+	return state, nil
+}
+
+// webauthnUsed stores the signature counter and the flags of the assertion
+func (rcv *AuthService) webauthnUsed(ctx context.Context, userID string, credential *webauthn.Credential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	err = rcv.queries.WebauthnCredentialUpdateByCredentialID(ctx, &dbs.WebauthnCredentialUpdateByCredentialIDParams{
+		Credential: data, UserID: userID, CredentialID: credential.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	return nil
+}
+
+// issueTokens completes the sign-in: new token family, session record, visited_at update
+func (rcv *AuthService) issueTokens(ctx context.Context, userID string, device *exchange.AuthDevice) (*exchange.AuthSigninRes, error) {
+	// generate tokens
+	accessToken, refreshToken, err := rcv.jwtProvider.GenerateTokens(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	claims, err := rcv.jwtProvider.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+
+	// update user visited_at
+	updated, err := rcv.queries.AuthUpdateVisitedAt(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+
+	// store refresh token in redis
+	if err := rcv.jwtProvider.StoreToken(refreshToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+
+	// record the signed-in device, the expired sessions of the user go away
+	if err := rcv.queries.UserSessionDeleteExpiredByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	err = rcv.queries.UserSessionNew(ctx, &dbs.UserSessionNewParams{
+		UserID:    userID,
+		FamilyID:  claims.FamilyID,
+		UserAgent: common.Truncate(device.UserAgent, 512),
+		IpAddress: common.Truncate(device.IpAddress, 64),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(rcv.refreshExpiration), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+
+	// response about logged in user
+	res := &exchange.AuthSigninRes{
+		User: &exchange.AuthUser{
+			ID:        updated.ID,
+			Username:  updated.Username,
+			CheckedAt: updated.CheckedAt,
+			VisitedAt: updated.VisitedAt,
+			CreatedAt: updated.CreatedAt,
+		},
+		Tokens: &exchange.AuthTokens{
+			Access:  accessToken,
+			Refresh: refreshToken,
+		},
+	}
+	return res, nil
+}
+
+// signinLock is the failed sign-in counter of a username or a client address
+type signinLock struct {
+	key       string
+	threshold int64
+}
+
+func (rcv *AuthService) signinLocks(username, ipAddress string) []*signinLock {
+	locks := []*signinLock{{key: signinLockKey(username), threshold: rcv.lockoutThreshold}}
+	if ipAddress != "" {
+		locks = append(locks, &signinLock{key: "ip:" + ipAddress, threshold: rcv.lockoutIpThreshold})
+	}
+	return locks
+}
+
+// signinLocked rejects the sign-in until the longest lockout is over
+func (rcv *AuthService) signinLocked(ctx context.Context, locks []*signinLock) error {
+	var retryAfter time.Duration
+	for _, lock := range locks {
+		ttl, err := rcv.redis.PTTL(ctx, keySigninLock+lock.key).Result()
+		if err == nil && ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	if retryAfter > 0 {
+		return &common.RetryError{
+			Err:        fmt.Errorf("%w: %v", common.ErrAuthLocked, "sign-in temporarily locked"),
+			RetryAfter: retryAfter,
+		}
+	}
+	return nil
+}
+
+// signinFailed counts the failed sign-in, past the threshold every failure
+// locks the sign-in out for twice as long as the previous one
+func (rcv *AuthService) signinFailed(ctx context.Context, locks []*signinLock, cause error) error {
+	var retryAfter time.Duration
+	for _, lock := range locks {
+		if lock.threshold <= 0 {
+			continue
+		}
+		failures, err := rcv.redis.Incr(ctx, keySigninFailures+lock.key).Result()
+		if err != nil {
+			slog.Error("signin failures", "key", lock.key, "error", err)
+			continue
+		}
+		rcv.redis.Expire(ctx, keySigninFailures+lock.key, rcv.lockoutWindow)
+		if failures < lock.threshold {
+			continue
+		}
+		lockout := rcv.lockoutDuration
+		for i := lock.threshold; i < failures && lockout < rcv.lockoutMaxDuration; i++ {
+			lockout *= 2
+		}
+		lockout = min(lockout, rcv.lockoutMaxDuration)
+		if err := rcv.redis.Set(ctx, keySigninLock+lock.key, failures, lockout).Err(); err != nil {
+			slog.Error("signin lockout", "key", lock.key, "error", err)
+			continue
+		}
+		retryAfter = max(retryAfter, lockout)
+	}
+	if retryAfter > 0 {
+		return &common.RetryError{Err: fmt.Errorf("%w: %v", common.ErrAuthLocked, cause), RetryAfter: retryAfter}
+	}
+	return cause
+}
+
+// unlockSignin lifts the lockout of the username and forgets its failed sign-ins
+func unlockSignin(redis *redis.Client, username string) error {
+	key := signinLockKey(username)
+	return redis.Del(context.Background(), keySigninFailures+key, keySigninLock+key).Err()
+}
+
+func signinLockKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// challengeFailed counts the failed attempt, a challenge survives a few typos only
+func (rcv *AuthService) challengeFailed(ctx context.Context, claims *provider.Claims, challenge string) error {
+	attempts, err := rcv.redis.Incr(ctx, keyChallengeAttempts+claims.ID).Result()
+	if err == nil {
+		rcv.redis.Expire(ctx, keyChallengeAttempts+claims.ID, rcv.challengeExpiration)
+	}
+	if err != nil || attempts >= maxChallengeAttempts {
+		rcv.jwtProvider.InvalidateToken(challenge)
+	}
+	return fmt.Errorf("%w: %v", common.Err2FAInvalidCode, errors.New("code mismatch"))
+}
+
+// storeRecoveryCodes replaces the user recovery codes, only the hashes are kept
+func (rcv *AuthService) storeRecoveryCodes(ctx context.Context, queries *dbs.Queries, userID string) ([]string, error) {
+	codes, err := rcv.twoFAProvider.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := queries.RecoveryCodeDeleteByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	for _, code := range codes {
+		err := queries.RecoveryCodeNew(ctx, &dbs.RecoveryCodeNewParams{
+			UserID: userID, CodeHash: rcv.twoFAProvider.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+		}
+	}
+	return codes, nil
+}
+
+// notifyRecoveryCodeUsed warns the user, a failed delivery does not break the sign-in
+func (rcv *AuthService) notifyRecoveryCodeUsed(ctx context.Context, userID string) {
+	left, err := rcv.queries.RecoveryCodeCountUnusedByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("recovery code notification", "user_id", userID, "error", err)
+		return
+	}
+	to, err := rcv.emails(ctx, userID)
+	if err != nil {
+		slog.Error("recovery code notification", "user_id", userID, "error", err)
+		return
+	}
+	err = rcv.mailerProvider.Send(&provider.Mail{
+		To:      to,
+		Subject: "Recovery code used",
+		Body: fmt.Sprintf(
+			"A recovery code was used to sign in to your account, %d codes left.\n"+
+				"If it was not you, change your password and regenerate the recovery codes.", left,
+		),
+	})
+	if err != nil {
+		slog.Error("recovery code notification", "user_id", userID, "error", err)
+	}
+}
+
+// validatePassword applies the password policy, the password must not contain
+// the username or any email of the user
+func (rcv *AuthService) validatePassword(ctx context.Context, userID, password string) error {
+	user, err := rcv.queries.UserSelectByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	emails, err := rcv.emails(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return rcv.passwordPolicy.Validate(password, append(emails, user.Username)...)
+}
+
+// rehashPassword stores the password hashed with the current algorithm and parameters
+func (rcv *AuthService) rehashPassword(ctx context.Context, userID, password string) error {
+	passwordCrypted, err := rcv.passwordHasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrPasswordHash, err)
+	}
+	_, err = rcv.queries.AuthUpdatePasswordByID(ctx, &dbs.AuthUpdatePasswordByIDParams{
+		ID: userID, Password: passwordCrypted,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+	}
+	return nil
+}
+
+// emails returns the user email contacts, the username is used when there are none
+func (rcv *AuthService) emails(ctx context.Context, userID string) ([]string, error) {
+	contacts, err := rcv.queries.ContactSelectByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	emails := []string{}
+	for _, contact := range contacts {
+		if contact.Class == ContactClassEmail {
+			emails = append(emails, contact.Content)
+		}
+	}
+	if len(emails) == 0 {
+		user, err := rcv.queries.UserSelectByID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+		emails = append(emails, user.Username)
+	}
+	return emails, nil
+}
+
+func (rcv *AuthService) profile(ctx context.Context, userID string) (*dbs.Profile, error) {
+	profile, err := rcv.queries.ProfileSelectByUserID(ctx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,640 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/urfave/cli/v3"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/internal/common"
+	"brickwall/internal/provider"
+
+	"brickwall/internal/storage/dbs"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	keyOAuthCode = "oauth:code:"
+
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+
+	oauthSecretSize = 32
+	oauthCodeSize   = 32
+)
+
+type IOAuthService interface {
+	// Client registration
+	OAuthClientNew(*exchange.OAuthClientNewReq) (*exchange.OAuthClientNewRes, error)
+	OAuthClientSelect() ([]*dbs.OauthClientSelectRow, error)
+	OAuthClientDeleteByID(string) error
+
+	// Authorization endpoint
+	Authorize(*common.Principal, *exchange.OAuthAuthorizeReq) (*exchange.OAuthAuthorizeRes, error)
+	AuthorizeConsent(*common.Principal, *exchange.OAuthAuthorizeConsentReq) (*exchange.OAuthAuthorizeRes, error)
+
+	// Token endpoint
+	Token(*exchange.OAuthTokenReq) (*exchange.OAuthTokenRes, error)
+
+	// Introspection endpoint
+	Introspect(*exchange.OAuthIntrospectReq) (*exchange.OAuthIntrospectRes, error)
+
+	// Consents
+	ConsentSelect(string) ([]*dbs.OauthConsentSelectByUserIDRow, error)
+	ConsentRevoke(string, string) error
+}
+
+// oauthCode is the authorization code grant kept in redis until it is redeemed
+type oauthCode struct {
+	UserID        string `json:"user_id"`
+	ClientID      string `json:"client_id"`
+	RedirectUri   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"`
+}
+
+// OAuthService is the OAuth2 authorization server. Users authorize clients with
+// the authorization code grant and PKCE, the consent is remembered per client.
+// Clients linked to a service account act on its behalf with client credentials.
+type OAuthService struct {
+	ctx     context.Context
+	queries *dbs.Queries
+	redis   *redis.Client
+
+	codeExpiration   time.Duration
+	accessExpiration time.Duration
+
+	jwtProvider provider.IJwtProvider
+}
+
+func NewOAuthService(ctx context.Context, queries *dbs.Queries) IOAuthService {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	return &OAuthService{
+		ctx:     ctx,
+		queries: queries,
+		redis:   ctx.Value(common.KeyRedisProvider).(provider.IRedisProvider).Client(),
+
+		codeExpiration:   cli.Duration("oauth-code-expiration"),
+		accessExpiration: cli.Duration("jwt-access-expiration"),
+
+		jwtProvider: ctx.Value(common.KeyJwtProvider).(provider.IJwtProvider),
+	}
+}
+
+// OAuthClientNew registers the client, the secret of confidential clients is
+// returned only once
+func (rcv *OAuthService) OAuthClientNew(req *exchange.OAuthClientNewReq) (*exchange.OAuthClientNewRes, error) {
+	ctx := context.Background()
+
+	if common.Contains(req.GrantTypes, GrantTypeAuthorizationCode) && len(req.RedirectUris) == 0 {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, "authorization_code requires redirect uris")
+	}
+	if common.Contains(req.GrantTypes, GrantTypeClientCredentials) {
+		if req.IsPublic || req.UserID == "" {
+			return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, "client_credentials requires a confidential client of a service account")
+		}
+		if _, err := rcv.queries.ServiceAccountSelectByID(ctx, req.UserID); err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+			} else {
+				return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+			}
+		}
+	}
+	for _, scope := range req.Scopes {
+		if _, err := rcv.queries.PermissionSelectByCode(ctx, scope); err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, fmt.Errorf("%w: unknown scope %s", common.ErrDBNotFound, scope)
+			} else {
+				return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+			}
+		}
+	}
+	params := &dbs.OauthClientNewParams{
+		Name:         req.Name,
+		IsPublic:     req.IsPublic,
+		RedirectUris: nonNil(req.RedirectUris),
+		GrantTypes:   req.GrantTypes,
+		Scopes:       nonNil(req.Scopes),
+		UserID:       pgtype.Text{String: req.UserID, Valid: req.UserID != ""},
+	}
+	secret := ""
+	if !req.IsPublic {
+		var err error
+		if secret, err = common.RandomToken(oauthSecretSize); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+		}
+		params.SecretHash = common.HashToken(secret)
+	}
+	row, err := rcv.queries.OauthClientNew(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return &exchange.OAuthClientNewRes{
+		ID:           row.ID,
+		Secret:       secret,
+		Name:         row.Name,
+		IsPublic:     row.IsPublic,
+		RedirectUris: row.RedirectUris,
+		GrantTypes:   row.GrantTypes,
+		Scopes:       row.Scopes,
+		UserID:       row.UserID.String,
+	}, nil
+}
+
+func (rcv *OAuthService) OAuthClientSelect() ([]*dbs.OauthClientSelectRow, error) {
+	res, err := rcv.queries.OauthClientSelect(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.OauthClientSelectRow{}
+	}
+	return res, nil
+}
+
+// OAuthClientDeleteByID removes the client together with the consents given to it
+func (rcv *OAuthService) OAuthClientDeleteByID(id string) error {
+	if _, err := rcv.queries.OauthClientDeleteByID(context.Background(), id); err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	return nil
+}
+
+// Authorize issues the code right away when the user already consented to the
+// requested scopes, otherwise it asks the user for the consent
+func (rcv *OAuthService) Authorize(principal *common.Principal, req *exchange.OAuthAuthorizeReq) (*exchange.OAuthAuthorizeRes, error) {
+	ctx := context.Background()
+
+	client, scopes, err := rcv.authorizeClient(req)
+	if err != nil {
+		return nil, err
+	}
+	res := &exchange.OAuthAuthorizeRes{
+		Client: &exchange.OAuthAuthorizeClient{ID: client.ID, Name: client.Name},
+		Scopes: scopes,
+	}
+	consent, err := rcv.queries.OauthConsentSelectByUserIDClientID(ctx, &dbs.OauthConsentSelectByUserIDClientIDParams{
+		UserID: principal.UserID, ClientID: client.ID,
+	})
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == pgx.ErrNoRows || !subset(scopes, consent.Scopes) {
+		res.ConsentRequired = true
+		return res, nil
+	}
+	if res.RedirectTo, err = rcv.issueCode(ctx, principal.UserID, req, scopes); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AuthorizeConsent records the user decision, a denial is reported back to the
+// client through the redirect uri
+func (rcv *OAuthService) AuthorizeConsent(principal *common.Principal, req *exchange.OAuthAuthorizeConsentReq) (*exchange.OAuthAuthorizeRes, error) {
+	ctx := context.Background()
+
+	client, scopes, err := rcv.authorizeClient(&req.OAuthAuthorizeReq)
+	if err != nil {
+		return nil, err
+	}
+	res := &exchange.OAuthAuthorizeRes{
+		Client: &exchange.OAuthAuthorizeClient{ID: client.ID, Name: client.Name},
+		Scopes: scopes,
+	}
+	if !req.Approve {
+		res.RedirectTo, err = redirect(req.RedirectUri, url.Values{
+			"error": {common.ErrOAuthAccessDenied.Error()}, "state": {req.State},
+		})
+		return res, err
+	}
+
+	// the consent grows with every approval, previously granted scopes stay granted
+	granted := scopes
+	consent, err := rcv.queries.OauthConsentSelectByUserIDClientID(ctx, &dbs.OauthConsentSelectByUserIDClientIDParams{
+		UserID: principal.UserID, ClientID: client.ID,
+	})
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if err == nil {
+		granted = union(consent.Scopes, scopes)
+	}
+	err = rcv.queries.OauthConsentUpsert(ctx, &dbs.OauthConsentUpsertParams{
+		UserID: principal.UserID, ClientID: client.ID, Scopes: granted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	if res.RedirectTo, err = rcv.issueCode(ctx, principal.UserID, &req.OAuthAuthorizeReq, scopes); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Token exchanges the grant for tokens, the client authenticates with its secret
+// unless it is public
+func (rcv *OAuthService) Token(req *exchange.OAuthTokenReq) (*exchange.OAuthTokenRes, error) {
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials:
+	default:
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthUnsupportedGrantType, req.GrantType)
+	}
+	client, err := rcv.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !common.Contains(client.GrantTypes, req.GrantType) {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthUnauthorizedClient, req.GrantType)
+	}
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return rcv.tokenAuthorizationCode(client, req)
+	case GrantTypeRefreshToken:
+		return rcv.tokenRefreshToken(client, req)
+	default:
+		return rcv.tokenClientCredentials(client, req)
+	}
+}
+
+// Introspect reports whether the token is active, the signature, the expiry, the
+// revocation list and the owner status are checked on every call. Tokens of
+// removed clients and blocked users are inactive, errors other than storage
+// failures never tell why.
+func (rcv *OAuthService) Introspect(req *exchange.OAuthIntrospectReq) (*exchange.OAuthIntrospectRes, error) {
+	ctx := context.Background()
+	inactive := &exchange.OAuthIntrospectRes{Active: false}
+
+	claims, err := rcv.jwtProvider.IntrospectToken(req.Token)
+	if err != nil {
+		return inactive, nil
+	}
+	tokenType := ""
+	switch claims.Type {
+	case provider.TokenTypeAccess:
+		tokenType = TokenTypeHintAccessToken
+	case provider.TokenTypeRefresh:
+		tokenType = TokenTypeHintRefreshToken
+	default:
+		return inactive, nil
+	}
+	if claims.ClientID != "" {
+		if _, err := rcv.client(claims.ClientID); err != nil {
+			if errors.Is(err, common.ErrOAuthInvalidClient) {
+				return inactive, nil
+			}
+			return nil, err
+		}
+	}
+	user, err := rcv.queries.UserSelectByID(ctx, claims.UserID())
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return inactive, nil
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if user.IsBlocked {
+		return inactive, nil
+	}
+	roles, err := rcv.queries.UserRoleSelectByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	res := &exchange.OAuthIntrospectRes{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  user.Username,
+		TokenType: tokenType,
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Roles:     []string{},
+	}
+	if claims.ExpiresAt != nil {
+		res.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		res.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		res.Nbf = claims.NotBefore.Unix()
+	}
+	for _, role := range roles {
+		res.Roles = append(res.Roles, role.Name)
+	}
+	return res, nil
+}
+
+func (rcv *OAuthService) ConsentSelect(userID string) ([]*dbs.OauthConsentSelectByUserIDRow, error) {
+	res, err := rcv.queries.OauthConsentSelectByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.OauthConsentSelectByUserIDRow{}
+	}
+	return res, nil
+}
+
+// ConsentRevoke withdraws the consent, refresh tokens held by the client stop
+// working immediately and its access tokens run out
+func (rcv *OAuthService) ConsentRevoke(userID, clientID string) error {
+	_, err := rcv.queries.OauthConsentDeleteByUserIDClientID(context.Background(), &dbs.OauthConsentDeleteByUserIDClientIDParams{
+		UserID: userID, ClientID: clientID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	return nil
+}
+
+func (rcv *OAuthService) tokenAuthorizationCode(client *dbs.OauthClient, req *exchange.OAuthTokenReq) (*exchange.OAuthTokenRes, error) {
+	ctx := context.Background()
+
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, "code and code_verifier are required")
+	}
+
+	// codes are single use, a replayed code finds nothing
+	data, err := rcv.redis.GetDel(ctx, keyOAuthCode+common.HashToken(req.Code)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "invalid or expired code")
+		}
+		return nil, err
+	}
+	code := &oauthCode{}
+	if err := json.Unmarshal(data, code); err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ID || code.RedirectUri != req.RedirectUri {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "code was issued to another client or redirect uri")
+	}
+	if subtle.ConstantTimeCompare([]byte(pkceChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "code verifier mismatch")
+	}
+	if err := rcv.activeUser(code.UserID); err != nil {
+		return nil, err
+	}
+	res := &exchange.OAuthTokenRes{
+		TokenType: "Bearer", ExpiresIn: int64(rcv.accessExpiration.Seconds()), Scope: code.Scope,
+	}
+	if !common.Contains(client.GrantTypes, GrantTypeRefreshToken) {
+		if res.AccessToken, err = rcv.jwtProvider.GenerateClientToken(code.UserID, client.ID, code.Scope); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+		}
+		return res, nil
+	}
+	res.AccessToken, res.RefreshToken, err = rcv.jwtProvider.GenerateClientTokens(code.UserID, client.ID, code.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	if err := rcv.jwtProvider.StoreToken(res.RefreshToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return res, nil
+}
+
+// tokenRefreshToken rotates the client tokens, the scope is carried forward and
+// is only honoured while the user consent still covers it
+func (rcv *OAuthService) tokenRefreshToken(client *dbs.OauthClient, req *exchange.OAuthTokenReq) (*exchange.OAuthTokenRes, error) {
+	claims, err := rcv.jwtProvider.ValidateToken(req.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, err)
+	}
+	if claims.Type != provider.TokenTypeRefresh || claims.ClientID != client.ID {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "refresh token was issued to another client")
+	}
+	consent, err := rcv.queries.OauthConsentSelectByUserIDClientID(context.Background(), &dbs.OauthConsentSelectByUserIDClientIDParams{
+		UserID: claims.UserID(), ClientID: client.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "consent revoked")
+		}
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if !subset(strings.Fields(claims.Scope), consent.Scopes) {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "consent revoked")
+	}
+	if err := rcv.activeUser(claims.UserID()); err != nil {
+		return nil, err
+	}
+	access, refresh, err := rcv.jwtProvider.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, err)
+	}
+	return &exchange.OAuthTokenRes{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(rcv.accessExpiration.Seconds()),
+		RefreshToken: refresh,
+		Scope:        claims.Scope,
+	}, nil
+}
+
+// tokenClientCredentials issues an access token of the linked service account,
+// there is no refresh token as the client can always authenticate again
+func (rcv *OAuthService) tokenClientCredentials(client *dbs.OauthClient, req *exchange.OAuthTokenReq) (*exchange.OAuthTokenRes, error) {
+	if client.IsPublic || !client.UserID.Valid {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthUnauthorizedClient, "client is not linked to a service account")
+	}
+	scopes, err := requestedScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := rcv.activeUser(client.UserID.String); err != nil {
+		return nil, err
+	}
+	scope := strings.Join(scopes, " ")
+
+	access, err := rcv.jwtProvider.GenerateClientToken(client.UserID.String, client.ID, scope)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	return &exchange.OAuthTokenRes{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(rcv.accessExpiration.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// authorizeClient validates the authorization request against the registered
+// client and returns the granted scopes
+func (rcv *OAuthService) authorizeClient(req *exchange.OAuthAuthorizeReq) (*dbs.OauthClient, []string, error) {
+	client, err := rcv.client(req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !common.Contains(client.GrantTypes, GrantTypeAuthorizationCode) {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrOAuthUnauthorizedClient, GrantTypeAuthorizationCode)
+	}
+	if !common.Contains(client.RedirectUris, req.RedirectUri) {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, "unregistered redirect uri")
+	}
+	scopes, err := requestedScopes(client, req.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, scopes, nil
+}
+
+// authenticateClient checks the client secret, public clients identify themselves only
+func (rcv *OAuthService) authenticateClient(clientID, clientSecret string) (*dbs.OauthClient, error) {
+	if clientID == "" {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidClient, "missing client id")
+	}
+	client, err := rcv.client(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic {
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(common.HashToken(clientSecret))) != 1 {
+		return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidClient, "invalid client credentials")
+	}
+	return client, nil
+}
+
+func (rcv *OAuthService) client(clientID string) (*dbs.OauthClient, error) {
+	res, err := rcv.queries.OauthClientSelectByID(context.Background(), clientID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidClient, "unknown client")
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	return res, nil
+}
+
+// activeUser rejects grants of users blocked or removed since the grant was issued
+func (rcv *OAuthService) activeUser(userID string) error {
+	user, err := rcv.queries.UserSelectByID(context.Background(), userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, "unknown user")
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if user.IsBlocked {
+		return fmt.Errorf("%w: %v", common.ErrOAuthInvalidGrant, common.ErrAuthUserBlocked)
+	}
+	return nil
+}
+
+// issueCode stores the single use code and returns the redirect back to the client
+func (rcv *OAuthService) issueCode(ctx context.Context, userID string, req *exchange.OAuthAuthorizeReq, scopes []string) (string, error) {
+	code, err := common.RandomToken(oauthCodeSize)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrAuthGenerateTokens, err)
+	}
+	data, err := json.Marshal(&oauthCode{
+		UserID:        userID,
+		ClientID:      req.ClientID,
+		RedirectUri:   req.RedirectUri,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := rcv.redis.Set(ctx, keyOAuthCode+common.HashToken(code), data, rcv.codeExpiration).Err(); err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	return redirect(req.RedirectUri, url.Values{"code": {code}, "state": {req.State}})
+}
+
+// requestedScopes checks the space separated scope against the client scopes,
+// an empty scope requests every scope of the client
+func requestedScopes(client *dbs.OauthClient, scope string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return nonNil(client.Scopes), nil
+	}
+	for _, scope := range scopes {
+		if !common.Contains(client.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %v", common.ErrOAuthInvalidScope, scope)
+		}
+	}
+	return union(nil, scopes), nil
+}
+
+// pkceChallenge derives the S256 code challenge of the verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// redirect appends the parameters to the redirect uri, empty values are left out
+func redirect(redirectUri string, params url.Values) (string, error) {
+	u, err := url.Parse(redirectUri)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrOAuthInvalidRequest, err)
+	}
+	qry := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			qry.Set(key, values[0])
+		}
+	}
+	u.RawQuery = qry.Encode()
+	return u.String(), nil
+}
+
+func subset(values, of []string) bool {
+	for _, value := range values {
+		if !common.Contains(of, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func union(values, more []string) []string {
+	res := nonNil(append([]string{}, values...))
+	for _, value := range more {
+		if !common.Contains(res, value) {
+			res = append(res, value)
+		}
+	}
+	return res
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
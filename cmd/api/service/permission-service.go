@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/urfave/cli/v3"
+
+	"brickwall/cmd/api/exchange"
+	"brickwall/internal/common"
+	"brickwall/internal/utils"
+
+	"brickwall/internal/storage/dbs"
+)
+
+type IPermissionService interface {
+	// CRUD operations
+	PermissionNew(*exchange.PermissionNewReq) (*dbs.Permission, error)
+	PermissionSelect(*gin.Context, *exchange.PermissionQuery) ([]*dbs.Permission, *utils.PaginatorResources, error)
+	PermissionSelectByID(string) (*dbs.Permission, error)
+	PermissionUpdateByID(*exchange.PermissionUpdateReq) (*dbs.Permission, error)
+	PermissionDeleteByID(string) error
+
+	// Business operations
+	RolePermissionSelect(string) ([]*dbs.Permission, error)
+	RolePermissionGrant(string, *exchange.RolePermissionGrantReq) ([]*dbs.Permission, error)
+	RolePermissionRevoke(*exchange.RolePermissionUri) ([]*dbs.Permission, error)
+
+	// Policy evaluation
+	Permissions([]string) ([]string, error)
+	Invalidate()
+}
+
+// PermissionService maintains the permission catalog and evaluates the role policy.
+// The policy is small and read on every request, so it is kept in process and
+// reloaded once the cache TTL passes or right after this instance changes it,
+// other instances pick the change up within the TTL.
+type PermissionService struct {
+	ctx      context.Context
+	queries  *dbs.Queries
+	cacheTTL time.Duration
+
+	mu       sync.RWMutex
+	policy   map[string][]string
+	loadedAt time.Time
+}
+
+func NewPermissionService(ctx context.Context, queries *dbs.Queries) IPermissionService {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	return &PermissionService{
+		ctx: ctx, queries: queries, cacheTTL: cli.Duration("auth-policy-cache-ttl"),
+	}
+}
+
+func (rcv *PermissionService) PermissionNew(req *exchange.PermissionNewReq) (*dbs.Permission, error) {
+	res, err := rcv.queries.PermissionNew(context.Background(), &dbs.PermissionNewParams{
+		Code: req.Code, Description: req.Description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	rcv.Invalidate()
+	return res, nil
+}
+
+func (rcv *PermissionService) PermissionSelect(c *gin.Context, qry *exchange.PermissionQuery) ([]*dbs.Permission, *utils.PaginatorResources, error) {
+	count, err := rcv.queries.PermissionCount(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrDBRecordCount, err)
+	}
+	paginator := utils.NewPaginator(c.Request, qry.Size, count)
+
+	params := &dbs.PermissionSelectParams{
+		SqlLimit:  int32(qry.Size),
+		SqlOffset: int32(paginator.Offset()),
+		SqlOrder:  c.DefaultQuery("order", "id"),
+	}
+	res, err := rcv.queries.PermissionSelect(context.Background(), params)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	pag := utils.NewPaginatorBuilder(paginator).Build()
+	return res, pag, nil
+}
+
+func (rcv *PermissionService) PermissionSelectByID(req string) (*dbs.Permission, error) {
+	res, err := rcv.queries.PermissionSelectByID(context.Background(), req)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	return res, nil
+}
+
+func (rcv *PermissionService) PermissionUpdateByID(req *exchange.PermissionUpdateReq) (*dbs.Permission, error) {
+	params := &dbs.PermissionUpdateByIDParams{
+		ID: req.ID, Code: req.Code, Description: req.Description,
+	}
+	res, err := rcv.queries.PermissionUpdateByID(context.Background(), params)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
+		}
+	}
+	rcv.Invalidate()
+	return res, nil
+}
+
+func (rcv *PermissionService) PermissionDeleteByID(id string) error {
+	if _, err := rcv.queries.PermissionDeleteByID(context.Background(), id); err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	rcv.Invalidate()
+	return nil
+}
+
+func (rcv *PermissionService) RolePermissionSelect(roleID string) ([]*dbs.Permission, error) {
+	if _, err := rcv.queries.RoleSelectByID(context.Background(), roleID); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	res, err := rcv.queries.RolePermissionSelectByRoleID(context.Background(), roleID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.Permission{}
+	}
+	return res, nil
+}
+
+// RolePermissionGrant adds the permission to the role, granting the same permission twice is a no-op
+func (rcv *PermissionService) RolePermissionGrant(roleID string, req *exchange.RolePermissionGrantReq) ([]*dbs.Permission, error) {
+	permission, err := rcv.permission(req.Permission)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rcv.RolePermissionSelect(roleID); err != nil {
+		return nil, err
+	}
+	err = rcv.queries.RolePermissionNew(context.Background(), &dbs.RolePermissionNewParams{
+		RoleID: roleID, PermissionID: permission.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	rcv.Invalidate()
+	return rcv.RolePermissionSelect(roleID)
+}
+
+func (rcv *PermissionService) RolePermissionRevoke(uri *exchange.RolePermissionUri) ([]*dbs.Permission, error) {
+	permission, err := rcv.permission(uri.Permission)
+	if err != nil {
+		return nil, err
+	}
+	_, err = rcv.queries.RolePermissionDeleteByRoleIDPermissionID(context.Background(), &dbs.RolePermissionDeleteByRoleIDPermissionIDParams{
+		RoleID: uri.ID, PermissionID: permission.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	rcv.Invalidate()
+	return rcv.RolePermissionSelect(uri.ID)
+}
+
+// Permissions returns the sorted union of the permissions granted to the roles
+func (rcv *PermissionService) Permissions(roles []string) ([]string, error) {
+	policy, err := rcv.load()
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, role := range roles {
+		for _, permission := range policy[role] {
+			if !common.Contains(res, permission) {
+				res = append(res, permission)
+			}
+		}
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+// Invalidate forces the policy to be reloaded on the next evaluation
+func (rcv *PermissionService) Invalidate() {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	rcv.policy = nil
+}
+
+// load returns the cached role policy, reloading it when it is stale
+func (rcv *PermissionService) load() (map[string][]string, error) {
+	rcv.mu.RLock()
+	policy, loadedAt := rcv.policy, rcv.loadedAt
+	rcv.mu.RUnlock()
+
+	if policy != nil && time.Since(loadedAt) < rcv.cacheTTL {
+		return policy, nil
+	}
+	rows, err := rcv.queries.RolePermissionSelectPolicy(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	policy = map[string][]string{}
+	for _, row := range rows {
+		policy[row.Role] = append(policy[row.Role], row.Permission)
+	}
+	rcv.mu.Lock()
+	rcv.policy, rcv.loadedAt = policy, time.Now()
+	rcv.mu.Unlock()
+
+	return policy, nil
+}
+
+func (rcv *PermissionService) permission(code string) (*dbs.Permission, error) {
+	res, err := rcv.queries.PermissionSelectByCode(context.Background(), code)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	return res, nil
+}
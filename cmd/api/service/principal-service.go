@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/urfave/cli/v3"
+
+	"brickwall/internal/common"
+	"brickwall/internal/provider"
+	"brickwall/internal/storage/dbs"
+)
+
+const keyPrincipal = "auth:principal:"
+
+type IPrincipalService interface {
+	Principal(string) (*common.Principal, error)
+	Invalidate(string) error
+}
+
+// PrincipalService loads the user status and roles for every authenticated
+// request, the result is cached briefly to keep the database out of the hot path.
+type PrincipalService struct {
+	ctx      context.Context
+	queries  *dbs.Queries
+	redis    *redis.Client
+	cacheTTL time.Duration
+}
+
+func NewPrincipalService(ctx context.Context, queries *dbs.Queries) IPrincipalService {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	return &PrincipalService{
+		ctx:      ctx,
+		queries:  queries,
+		redis:    ctx.Value(common.KeyRedisProvider).(provider.IRedisProvider).Client(),
+		cacheTTL: cli.Duration("auth-principal-cache-ttl"),
+	}
+}
+
+func (rcv *PrincipalService) Principal(userID string) (*common.Principal, error) {
+	ctx := context.Background()
+
+	if cached, err := rcv.redis.Get(ctx, keyPrincipal+userID).Bytes(); err == nil {
+		principal := &common.Principal{}
+		if err := json.Unmarshal(cached, principal); err == nil {
+			return principal, nil
+		}
+	}
+	user, err := rcv.queries.UserSelectByID(ctx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	roles, err := rcv.queries.UserRoleSelectByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	principal := &common.Principal{
+		UserID:    user.ID,
+		Username:  user.Username,
+		IsBlocked: user.IsBlocked,
+		IsChecked: user.IsChecked,
+		Roles:     []string{},
+	}
+	for _, role := range roles {
+		principal.Roles = append(principal.Roles, role.Name)
+	}
+	if raw, err := json.Marshal(principal); err == nil {
+		rcv.redis.Set(ctx, keyPrincipal+userID, raw, rcv.cacheTTL)
+	}
+	return principal, nil
+}
+
+// Invalidate drops the cached principal, the next request reloads it
+func (rcv *PrincipalService) Invalidate(userID string) error {
+	return invalidatePrincipal(rcv.redis, userID)
+}
+
+// authorizeOwner lets owners act on their own resources, acting on resources
+// of another user requires the admin permission. Api keys and client tokens act
+// on the owner resources only when their scopes grant the permission.
+func authorizeOwner(principal *common.Principal, ownerID, permission string) error {
+	if principal == nil {
+		return fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "missing principal")
+	}
+	if principal.Owns(ownerID) && principal.InScope(permission) || principal.HasPermission(permission) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s required to act on another user", common.ErrAuthForbidden, permission)
+}
+
+func invalidatePrincipal(redis *redis.Client, userID string) error {
+	return redis.Del(context.Background(), keyPrincipal+userID).Err()
+}
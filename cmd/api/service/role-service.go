@@ -6,9 +6,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
 
 	"brickwall/cmd/api/exchange"
 	"brickwall/internal/common"
+	"brickwall/internal/provider"
 	"brickwall/internal/utils"
 
 	"brickwall/internal/storage/dbs"
@@ -21,15 +23,29 @@ type IRoleService interface {
 	RoleSelectByID(string) (*dbs.Role, error)
 	RoleUpdateByID(*exchange.RoleUpdateReq) (*dbs.Role, error)
 	RoleDeleteByID(string) error
+
+	// Business operations
+	UserRoleSelect(string) ([]*dbs.Role, error)
+	UserRoleGrant(*common.Principal, string, *exchange.UserRoleGrantReq) ([]*dbs.Role, error)
+	UserRoleRevoke(*common.Principal, *exchange.UserRoleUri) ([]*dbs.Role, error)
 }
 
+// RoleService maintains the role catalog and the assignments. The policy and the
+// principals refer to the roles by name, so renaming or deleting a role reloads both.
 type RoleService struct {
-	ctx     context.Context
-	queries *dbs.Queries
+	ctx               context.Context
+	queries           *dbs.Queries
+	redis             *redis.Client
+	permissionService IPermissionService
 }
 
-func NewRoleService(ctx context.Context, queries *dbs.Queries) IRoleService {
-	return &RoleService{ctx: ctx, queries: queries}
+func NewRoleService(ctx context.Context, queries *dbs.Queries, permissionService IPermissionService) IRoleService {
+	return &RoleService{
+		ctx:               ctx,
+		queries:           queries,
+		redis:             ctx.Value(common.KeyRedisProvider).(provider.IRedisProvider).Client(),
+		permissionService: permissionService,
+	}
 }
 
 func (rcv *RoleService) RoleNew(req *exchange.RoleNewReq) (*dbs.Role, error) {
@@ -88,10 +104,18 @@ func (rcv *RoleService) RoleUpdateByID(req *exchange.RoleUpdateReq) (*dbs.Role,
 			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
 		}
 	}
+	if err := rcv.invalidate(res.ID); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
 func (rcv *RoleService) RoleDeleteByID(id string) error {
+	// the assignments are gone along with the role, the holders are collected first
+	userIDs, err := rcv.queries.UserRoleSelectUserIDByRoleID(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
 	if _, err := rcv.queries.RoleDeleteByID(context.Background(), id); err != nil {
 		if err == pgx.ErrNoRows {
 			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
@@ -99,5 +123,95 @@ func (rcv *RoleService) RoleDeleteByID(id string) error {
 			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
 		}
 	}
+	rcv.permissionService.Invalidate()
+	for _, userID := range userIDs {
+		invalidatePrincipal(rcv.redis, userID)
+	}
 	return nil
 }
+
+func (rcv *RoleService) UserRoleSelect(userID string) ([]*dbs.Role, error) {
+	if _, err := rcv.queries.UserSelectByID(context.Background(), userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	res, err := rcv.queries.UserRoleSelectByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	if res == nil {
+		res = []*dbs.Role{}
+	}
+	return res, nil
+}
+
+// UserRoleGrant assigns the role to the user, granting the same role twice is a no-op
+func (rcv *RoleService) UserRoleGrant(principal *common.Principal, userID string, req *exchange.UserRoleGrantReq) ([]*dbs.Role, error) {
+	role, err := rcv.assignable(principal, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rcv.UserRoleSelect(userID); err != nil {
+		return nil, err
+	}
+	err = rcv.queries.UserRoleNew(context.Background(), &dbs.UserRoleNewParams{
+		UserID: userID, RoleID: role.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
+	}
+	invalidatePrincipal(rcv.redis, userID)
+	return rcv.UserRoleSelect(userID)
+}
+
+func (rcv *RoleService) UserRoleRevoke(principal *common.Principal, uri *exchange.UserRoleUri) ([]*dbs.Role, error) {
+	role, err := rcv.assignable(principal, uri.Role)
+	if err != nil {
+		return nil, err
+	}
+	_, err = rcv.queries.UserRoleDeleteByUserIDRoleID(context.Background(), &dbs.UserRoleDeleteByUserIDRoleIDParams{
+		UserID: uri.ID, RoleID: role.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+		}
+	}
+	invalidatePrincipal(rcv.redis, uri.ID)
+	return rcv.UserRoleSelect(uri.ID)
+}
+
+// invalidate reloads the policy and the principals of the role holders
+func (rcv *RoleService) invalidate(roleID string) error {
+	rcv.permissionService.Invalidate()
+
+	userIDs, err := rcv.queries.UserRoleSelectUserIDByRoleID(context.Background(), roleID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	for _, userID := range userIDs {
+		invalidatePrincipal(rcv.redis, userID)
+	}
+	return nil
+}
+
+// assignable resolves the role by name, only SYS may grant or revoke SYS
+func (rcv *RoleService) assignable(principal *common.Principal, name string) (*dbs.Role, error) {
+	if name == common.RoleSys && !principal.HasRole(common.RoleSys) {
+		return nil, fmt.Errorf("%w: only %s may assign %s", common.ErrAuthForbidden, common.RoleSys, common.RoleSys)
+	}
+	role, err := rcv.queries.RoleSelectByName(context.Background(), name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	return role, nil
+}
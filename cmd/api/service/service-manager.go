@@ -12,7 +12,11 @@ type IServiceManager interface {
 	AuxService() IAuxService
 	UserService() IUserService
 	AuthService() IAuthService
+	PrincipalService() IPrincipalService
+	ApiKeyService() IApiKeyService
+	OAuthService() IOAuthService
 	RoleService() IRoleService
+	PermissionService() IPermissionService
 	CountryService() ICountryService
 	CurrencyService() ICurrencyService
 }
@@ -20,28 +24,37 @@ type ServiceManager struct {
 	ctx     context.Context
 	queries *dbs.Queries
 
-	auxService      IAuxService
-	userService     IUserService
-	authService     IAuthService
-	roleService     IRoleService
-	countryService  ICountryService
-	currencyService ICurrencyService
+	auxService        IAuxService
+	userService       IUserService
+	authService       IAuthService
+	principalService  IPrincipalService
+	apiKeyService     IApiKeyService
+	oauthService      IOAuthService
+	roleService       IRoleService
+	permissionService IPermissionService
+	countryService    ICountryService
+	currencyService   ICurrencyService
 }
 
 func NewServiceManager(ctx context.Context) IServiceManager {
 	pgxProvider := ctx.Value(common.KeyPgxProvider).(provider.IPgxProvider)
 	queries := dbs.New(pgxProvider.Pool())
+	permissionService := NewPermissionService(ctx, queries)
 
 	return &ServiceManager{
 		ctx:     ctx,
 		queries: queries,
 
-		auxService:      NewAuxService(ctx, queries),
-		userService:     NewUserService(ctx, queries),
-		authService:     NewAuthService(ctx, queries),
-		roleService:     NewRoleService(ctx, queries),
-		countryService:  NewCountryService(ctx, queries),
-		currencyService: NewCurrencyService(ctx, queries),
+		auxService:        NewAuxService(ctx, queries),
+		userService:       NewUserService(ctx, queries),
+		authService:       NewAuthService(ctx, queries),
+		principalService:  NewPrincipalService(ctx, queries),
+		apiKeyService:     NewApiKeyService(ctx, queries),
+		oauthService:      NewOAuthService(ctx, queries),
+		roleService:       NewRoleService(ctx, queries, permissionService),
+		permissionService: permissionService,
+		countryService:    NewCountryService(ctx, queries),
+		currencyService:   NewCurrencyService(ctx, queries),
 	}
 }
 
@@ -57,10 +70,26 @@ func (rcv *ServiceManager) AuthService() IAuthService {
 	return rcv.authService
 }
 
+func (rcv *ServiceManager) PrincipalService() IPrincipalService {
+	return rcv.principalService
+}
+
+func (rcv *ServiceManager) ApiKeyService() IApiKeyService {
+	return rcv.apiKeyService
+}
+
+func (rcv *ServiceManager) OAuthService() IOAuthService {
+	return rcv.oauthService
+}
+
 func (rcv *ServiceManager) RoleService() IRoleService {
 	return rcv.roleService
 }
 
+func (rcv *ServiceManager) PermissionService() IPermissionService {
+	return rcv.permissionService
+}
+
 func (rcv *ServiceManager) CountryService() ICountryService {
 	return rcv.countryService
 }
@@ -6,10 +6,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/redis/go-redis/v9"
 
 	"brickwall/cmd/api/exchange"
 	"brickwall/internal/common"
+	"brickwall/internal/provider"
 	"brickwall/internal/utils"
 
 	"brickwall/internal/storage/dbs"
@@ -21,30 +22,46 @@ type IUserService interface {
 	UserSelect(*gin.Context, *exchange.UserQuery) ([]*dbs.UserSelectRow, *utils.PaginatorResources, error)
 	UserSelectByID(string) (*dbs.UserSelectByIDRow, error)
 	UserSelectByUsername(string) (*dbs.UserSelectByUsernameRow, error)
-	UserDeleteByID(string) error
+	UserDeleteByID(*common.Principal, string) error
 
 	// Business operations
-	UserUpdateCredentialsByID(*exchange.UserUpdateCredentialsReq) (*dbs.UserUpdateCredentialsByIDRow, error)
-	UserUpdateIsBlockedByID(*exchange.UserUpdateIsBlockedByIDReq) (*dbs.UserUpdateIsBlockedByIDRow, error)
-	UserUpdateIsCheckedByID(*exchange.UserUpdateIsCheckedByIDReq) (*dbs.UserUpdateIsCheckedByIDRow, error)
+	UserUpdateCredentialsByID(*common.Principal, *exchange.UserUpdateCredentialsReq) (*dbs.UserUpdateCredentialsByIDRow, error)
+	UserUpdateIsBlockedByID(*common.Principal, *exchange.UserUpdateIsBlockedByIDReq) (*dbs.UserUpdateIsBlockedByIDRow, error)
+	UserUpdateIsCheckedByID(*common.Principal, *exchange.UserUpdateIsCheckedByIDReq) (*dbs.UserUpdateIsCheckedByIDRow, error)
 	UserUpdateVisitedAtByID(*exchange.UserUpdateVisitedAtByIDReq) (*dbs.UserUpdateVisitedAtByIDRow, error)
+	UserUnlockByID(*common.Principal, string) error
 }
 
 type UserService struct {
-	ctx     context.Context
-	queries *dbs.Queries
+	ctx            context.Context
+	queries        *dbs.Queries
+	redis          *redis.Client
+	passwordPolicy provider.IPasswordPolicyProvider
+	passwordHasher provider.IPasswordHasherProvider
 }
 
 func NewUserService(ctx context.Context, queries *dbs.Queries) IUserService {
-	return &UserService{ctx: ctx, queries: queries}
+	return &UserService{
+		ctx:            ctx,
+		queries:        queries,
+		redis:          ctx.Value(common.KeyRedisProvider).(provider.IRedisProvider).Client(),
+		passwordPolicy: ctx.Value(common.KeyPasswordPolicy).(provider.IPasswordPolicyProvider),
+		passwordHasher: ctx.Value(common.KeyPasswordHasher).(provider.IPasswordHasherProvider),
+	}
 }
 
 func (rcv *UserService) UserNew(req *exchange.UserNewReq) (*dbs.UserNewRow, error) {
-	passwordCrypted, _ := bcrypt.GenerateFromPassword([]byte(req.Password), 12)
+	if err := rcv.passwordPolicy.Validate(req.Password, req.Username); err != nil {
+		return nil, err
+	}
+	passwordCrypted, err := rcv.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrPasswordHash, err)
+	}
 
 	res, err := rcv.queries.UserNew(context.Background(), &dbs.UserNewParams{
 		Username: req.Username,
-		Password: string(passwordCrypted),
+		Password: passwordCrypted,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", common.ErrDBRecordInsert, err)
@@ -100,11 +117,20 @@ func (rcv *UserService) UserSelectByUsername(req string) (*dbs.UserSelectByUsern
 	return res, nil
 }
 
-func (rcv *UserService) UserUpdateCredentialsByID(req *exchange.UserUpdateCredentialsReq) (*dbs.UserUpdateCredentialsByIDRow, error) {
-	passwordCrypted, _ := bcrypt.GenerateFromPassword([]byte(req.Password), 12)
+func (rcv *UserService) UserUpdateCredentialsByID(principal *common.Principal, req *exchange.UserUpdateCredentialsReq) (*dbs.UserUpdateCredentialsByIDRow, error) {
+	if err := rcv.authorize(principal, req.ID, common.PermUserWrite); err != nil {
+		return nil, err
+	}
+	if err := rcv.passwordPolicy.Validate(req.Password, req.Username); err != nil {
+		return nil, err
+	}
+	passwordCrypted, err := rcv.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrPasswordHash, err)
+	}
 
 	params := &dbs.UserUpdateCredentialsByIDParams{
-		ID: req.ID, Username: req.Username, Password: string(passwordCrypted),
+		ID: req.ID, Username: req.Username, Password: passwordCrypted,
 	}
 	res, err := rcv.queries.UserUpdateCredentialsByID(context.Background(), params)
 	if err != nil {
@@ -114,10 +140,14 @@ func (rcv *UserService) UserUpdateCredentialsByID(req *exchange.UserUpdateCreden
 			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordUpdate, err)
 		}
 	}
+	invalidatePrincipal(rcv.redis, res.ID)
 	return res, nil
 }
 
-func (rcv *UserService) UserUpdateIsBlockedByID(req *exchange.UserUpdateIsBlockedByIDReq) (*dbs.UserUpdateIsBlockedByIDRow, error) {
+func (rcv *UserService) UserUpdateIsBlockedByID(principal *common.Principal, req *exchange.UserUpdateIsBlockedByIDReq) (*dbs.UserUpdateIsBlockedByIDRow, error) {
+	if err := rcv.authorizeAdmin(principal, req.ID, common.PermUserBlock); err != nil {
+		return nil, err
+	}
 	params := &dbs.UserUpdateIsBlockedByIDParams{
 		ID:        req.ID,
 		IsBlocked: req.IsBlocked,
@@ -130,10 +160,14 @@ func (rcv *UserService) UserUpdateIsBlockedByID(req *exchange.UserUpdateIsBlocke
 			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
 		}
 	}
+	invalidatePrincipal(rcv.redis, res.ID)
 	return res, nil
 }
 
-func (rcv *UserService) UserUpdateIsCheckedByID(req *exchange.UserUpdateIsCheckedByIDReq) (*dbs.UserUpdateIsCheckedByIDRow, error) {
+func (rcv *UserService) UserUpdateIsCheckedByID(principal *common.Principal, req *exchange.UserUpdateIsCheckedByIDReq) (*dbs.UserUpdateIsCheckedByIDRow, error) {
+	if err := rcv.authorizeAdmin(principal, req.ID, common.PermUserWrite); err != nil {
+		return nil, err
+	}
 	params := &dbs.UserUpdateIsCheckedByIDParams{
 		ID:        req.ID,
 		IsChecked: req.IsChecked,
@@ -146,6 +180,7 @@ func (rcv *UserService) UserUpdateIsCheckedByID(req *exchange.UserUpdateIsChecke
 			return nil, fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
 		}
 	}
+	invalidatePrincipal(rcv.redis, res.ID)
 	return res, nil
 }
 
@@ -165,7 +200,10 @@ func (rcv *UserService) UserUpdateVisitedAtByID(req *exchange.UserUpdateVisitedA
 	return res, nil
 }
 
-func (rcv *UserService) UserDeleteByID(id string) error {
+func (rcv *UserService) UserDeleteByID(principal *common.Principal, id string) error {
+	if err := rcv.authorize(principal, id, common.PermUserDelete); err != nil {
+		return err
+	}
 	if _, err := rcv.queries.UserDeleteByID(context.Background(), id); err != nil {
 		if err == pgx.ErrNoRows {
 			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
@@ -173,5 +211,65 @@ func (rcv *UserService) UserDeleteByID(id string) error {
 			return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
 		}
 	}
+	invalidatePrincipal(rcv.redis, id)
+	return nil
+}
+
+// UserUnlockByID lifts the sign-in lockout of the user before it expires
+func (rcv *UserService) UserUnlockByID(principal *common.Principal, id string) error {
+	if err := rcv.authorizeAdmin(principal, id, common.PermUserBlock); err != nil {
+		return err
+	}
+	user, err := rcv.queries.UserSelectByID(context.Background(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %v", common.ErrDBNotFound, err)
+		} else {
+			return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+		}
+	}
+	if err := unlockSignin(rcv.redis, user.Username); err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordDelete, err)
+	}
+	return nil
+}
+
+// authorize applies the ownership policy to the target user, accounts holding SYS
+// can be changed by SYS only so the admin permission cannot be used to take them over
+func (rcv *UserService) authorize(principal *common.Principal, userID, permission string) error {
+	if err := authorizeOwner(principal, userID, permission); err != nil {
+		return err
+	}
+	if principal.Owns(userID) {
+		return nil
+	}
+	return rcv.authorizeSys(principal, userID)
+}
+
+// authorizeAdmin requires the permission even on the own account, for the flags
+// an owner must not set on itself
+func (rcv *UserService) authorizeAdmin(principal *common.Principal, userID, permission string) error {
+	if principal == nil {
+		return fmt.Errorf("%w: %v", common.ErrAuthUnauthenticated, "missing principal")
+	}
+	if !principal.HasPermission(permission) {
+		return fmt.Errorf("%w: %s required", common.ErrAuthForbidden, permission)
+	}
+	return rcv.authorizeSys(principal, userID)
+}
+
+func (rcv *UserService) authorizeSys(principal *common.Principal, userID string) error {
+	if principal.HasRole(common.RoleSys) {
+		return nil
+	}
+	roles, err := rcv.queries.UserRoleSelectByUserID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrDBRecordSelect, err)
+	}
+	for _, role := range roles {
+		if role.Name == common.RoleSys {
+			return fmt.Errorf("%w: only %s may act on %s users", common.ErrAuthForbidden, common.RoleSys, common.RoleSys)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,33 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSignout(t *testing.T) {
+	h := newHarness(t)
+	h.addUser("u1", "alice@example.com", "Secret123")
+
+	var body struct {
+		Content struct {
+			Tokens struct {
+				Access  string `json:"access"`
+				Refresh string `json:"refresh"`
+			} `json:"tokens"`
+		} `json:"content"`
+	}
+	h.do(http.MethodPost, "/api/v1/auth/signin", "", map[string]string{"username": "alice@example.com", "password": "Secret123"}).
+		status(http.StatusOK).decode(&body)
+	tokens := body.Content.Tokens
+	signout := map[string]string{"refresh": tokens.Refresh}
+
+	// the refresh token is not an access token
+	h.do(http.MethodPost, "/api/v1/auth/signout", bearer(tokens.Refresh), signout).status(http.StatusUnauthorized)
+
+	h.do(http.MethodPost, "/api/v1/auth/signout", bearer(tokens.Access), signout).status(http.StatusOK)
+
+	// both tokens are revoked
+	h.do(http.MethodGet, "/api/v1/auth/me", bearer(tokens.Access), nil).status(http.StatusUnauthorized)
+	h.do(http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{"token": tokens.Refresh}).status(http.StatusUnauthorized)
+}
@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"brickwall/internal/common"
+)
+
+func newUserHarness(t *testing.T) *harness {
+	h := newHarness(t)
+	h.db.policy[common.RoleAdmin] = []string{common.PermUserRead, common.PermUserWrite}
+	h.addUser("u1", "alice@example.com", "Secret123")
+	h.addUser("u2", "bob@example.com", "Secret123")
+	h.addUser("a1", "admin@example.com", "Secret123", common.RoleAdmin)
+	h.addUser("s1", "root@example.com", "Secret123", common.RoleSys)
+	return h
+}
+
+func credentials(id, username string) map[string]string {
+	return map[string]string{"id": id, "username": username, "password": "Changed456"}
+}
+
+func TestUserCredentialsOwnership(t *testing.T) {
+	h := newUserHarness(t)
+	session := bearer(h.signin("alice@example.com", "Secret123"))
+
+	// the owner changes the own credentials without the admin permission
+	h.do(http.MethodPut, "/api/v1/user/section/credentials", session, credentials("u1", "alice@example.com")).status(http.StatusOK)
+	h.signin("alice@example.com", "Changed456")
+
+	// but not the credentials of another user
+	h.do(http.MethodPut, "/api/v1/user/section/credentials", session, credentials("u2", "bob@example.com")).status(http.StatusForbidden)
+	h.signin("bob@example.com", "Secret123")
+}
+
+func TestUserCredentialsSysTarget(t *testing.T) {
+	h := newUserHarness(t)
+	session := bearer(h.signin("admin@example.com", "Secret123"))
+
+	h.do(http.MethodPut, "/api/v1/user/section/credentials", session, credentials("u2", "bob@example.com")).status(http.StatusOK)
+
+	// the admin permission does not take over the SYS accounts
+	h.do(http.MethodPut, "/api/v1/user/section/credentials", session, credentials("s1", "root@example.com")).status(http.StatusForbidden)
+	h.signin("root@example.com", "Secret123")
+}
+
+func TestUserIsCheckedAdminOnly(t *testing.T) {
+	h := newUserHarness(t)
+	h.db.users["u2"].IsChecked = false
+	checked := map[string]any{"id": "u1", "is_checked": true}
+
+	// the owner does not verify its own account
+	session := bearer(h.signin("alice@example.com", "Secret123"))
+	h.do(http.MethodPut, "/api/v1/user/section/is_checked", session, checked).status(http.StatusForbidden)
+
+	session = bearer(h.signin("admin@example.com", "Secret123"))
+	h.do(http.MethodPut, "/api/v1/user/section/is_checked", session, map[string]any{"id": "u2", "is_checked": true}).status(http.StatusOK)
+	if !h.db.users["u2"].IsChecked {
+		t.Fatal("admin did not verify the account")
+	}
+}
@@ -0,0 +1,317 @@
+package api_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	webauthnRPID   = "localhost"
+	webauthnOrigin = "http://localhost:8081"
+
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// softAuthenticator is a platform authenticator holding one P-256 passkey, it
+// attests with the none format and counts the signatures
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	origin       string
+	counter      uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &softAuthenticator{t: t, key: key, credentialID: credentialID, origin: webauthnOrigin}
+}
+
+func (rcv *softAuthenticator) clientData(ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) []byte {
+	data, err := json.Marshal(&protocol.CollectedClientData{
+		Type: ceremony, Challenge: base64.RawURLEncoding.EncodeToString(challenge), Origin: rcv.origin,
+	})
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	return data
+}
+
+func (rcv *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(webauthnRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, rcv.counter)
+	return append(data, attested...)
+}
+
+// create answers navigator.credentials.create with the new passkey
+func (rcv *softAuthenticator) create(options *protocol.CredentialCreation) json.RawMessage {
+	rcv.t.Helper()
+
+	point, err := rcv.key.PublicKey.ECDH()
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	uncompressed := point.Bytes()
+	publicKey, err := webauthncbor.Marshal(&webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType: int64(webauthncose.EllipticKey), Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: uncompressed[1:33],
+		YCoord: uncompressed[33:],
+	})
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	attested := make([]byte, 16) // no aaguid
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(rcv.credentialID)))
+	attested = append(append(attested, rcv.credentialID...), publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": rcv.authData(flagUserPresent|flagUserVerified|flagAttested, attested),
+	})
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	if id, ok := options.Response.User.ID.(string); ok {
+		rcv.userHandle, _ = base64.RawURLEncoding.DecodeString(id)
+	}
+	return rcv.marshal(map[string]any{
+		"clientDataJSON":    rcv.clientData(protocol.CreateCeremony, options.Response.Challenge),
+		"attestationObject": attestation,
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers navigator.credentials.get with a signed assertion
+func (rcv *softAuthenticator) get(options *protocol.CredentialAssertion) json.RawMessage {
+	rcv.t.Helper()
+
+	rcv.counter++
+	authData := rcv.authData(flagUserPresent|flagUserVerified, nil)
+	clientData := rcv.clientData(protocol.AssertCeremony, options.Response.Challenge)
+	digest := sha256.Sum256(append(authData, hash(clientData)...))
+	signature, err := ecdsa.SignASN1(rand.Reader, rcv.key, digest[:])
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	return rcv.marshal(map[string]any{
+		"clientDataJSON":    clientData,
+		"authenticatorData": authData,
+		"signature":         signature,
+		"userHandle":        rcv.userHandle,
+	})
+}
+
+// marshal wraps the authenticator response, the binary members are base64url
+// encoded the way the browser serializes the PublicKeyCredential
+func (rcv *softAuthenticator) marshal(response map[string]any) json.RawMessage {
+	encoded := map[string]any{}
+	for name, value := range response {
+		if data, ok := value.([]byte); ok {
+			value = base64.RawURLEncoding.EncodeToString(data)
+		}
+		encoded[name] = value
+	}
+	id := base64.RawURLEncoding.EncodeToString(rcv.credentialID)
+	data, err := json.Marshal(map[string]any{
+		"id": id, "rawId": id, "type": "public-key", "response": encoded,
+		"authenticatorAttachment": "platform", "clientExtensionResults": map[string]any{},
+	})
+	if err != nil {
+		rcv.t.Fatal(err)
+	}
+	return data
+}
+
+func hash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// webauthnBegin starts the ceremony and decodes the options into v
+func webauthnBegin(h *harness, path, session string, body any, v any) string {
+	h.t.Helper()
+
+	if session != "" {
+		session = bearer(session)
+	}
+	var res struct {
+		Content struct {
+			State   string          `json:"state"`
+			Options json.RawMessage `json:"options"`
+		} `json:"content"`
+	}
+	h.do(http.MethodPost, path, session, body).status(http.StatusOK).decode(&res)
+	if err := json.Unmarshal(res.Content.Options, v); err != nil {
+		h.t.Fatal(err)
+	}
+	return res.Content.State
+}
+
+// register adds the passkey of the authenticator to the signed-in user
+func register(h *harness, session string, authenticator *softAuthenticator) *response {
+	h.t.Helper()
+
+	options := &protocol.CredentialCreation{}
+	state := webauthnBegin(h, "/api/v1/auth/webauthn/register/begin", session, nil, options)
+	return h.do(http.MethodPost, "/api/v1/auth/webauthn/register/finish", bearer(session), map[string]any{
+		"name": "laptop", "state": state, "credential": authenticator.create(options),
+	})
+}
+
+// passwordChallenge signs in with the password and returns the second factor challenge
+func passwordChallenge(h *harness, username, password string) (string, []string) {
+	h.t.Helper()
+
+	var body struct {
+		Content struct {
+			Challenge *struct {
+				Token   string   `json:"token"`
+				Methods []string `json:"methods"`
+			} `json:"challenge"`
+		} `json:"content"`
+	}
+	res := h.do(http.MethodPost, "/api/v1/auth/signin", "", map[string]string{"username": username, "password": password})
+	res.status(http.StatusOK).decode(&body)
+	if body.Content.Challenge == nil {
+		h.t.Fatalf("signin of %s returned no challenge: %s", username, res.body)
+	}
+	return body.Content.Challenge.Token, body.Content.Challenge.Methods
+}
+
+func newWebAuthnHarness(t *testing.T) *harness {
+	h := newHarness(t)
+	h.addUser("u1", "alice@example.com", "Secret123")
+	return h
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	h := newWebAuthnHarness(t)
+	session := h.signin("alice@example.com", "Secret123")
+
+	// the attestation of another origin is refused
+	phishing := newSoftAuthenticator(t)
+	phishing.origin = "https://brickwall.example.net"
+	register(h, session, phishing).status(http.StatusUnauthorized)
+
+	authenticator := newSoftAuthenticator(t)
+	register(h, session, authenticator).status(http.StatusCreated)
+	if len(h.db.passkeys) != 1 || h.db.passkeys[0].UserID != "u1" || string(authenticator.userHandle) != "u1" {
+		t.Fatalf("passkey not stored for the user: %+v", h.db.passkeys)
+	}
+
+	// the state is single use
+	options := &protocol.CredentialCreation{}
+	state := webauthnBegin(h, "/api/v1/auth/webauthn/register/begin", session, nil, options)
+	finish := map[string]any{"state": state, "credential": newSoftAuthenticator(t).create(options)}
+	h.do(http.MethodPost, "/api/v1/auth/webauthn/register/finish", bearer(session), finish).status(http.StatusCreated)
+	h.do(http.MethodPost, "/api/v1/auth/webauthn/register/finish", bearer(session), finish).status(http.StatusBadRequest)
+
+	// a passkey alone does not turn the second factor on
+	h.signin("alice@example.com", "Secret123")
+}
+
+func TestWebAuthnPasskeyLogin(t *testing.T) {
+	h := newWebAuthnHarness(t)
+	authenticator := newSoftAuthenticator(t)
+	register(h, h.signin("alice@example.com", "Secret123"), authenticator).status(http.StatusCreated)
+
+	login := func(authenticator *softAuthenticator) (*response, map[string]any) {
+		options := &protocol.CredentialAssertion{}
+		state := webauthnBegin(h, "/api/v1/auth/webauthn/login/begin", "", nil, options)
+		finish := map[string]any{"state": state, "credential": authenticator.get(options)}
+		return h.do(http.MethodPost, "/api/v1/auth/webauthn/login/finish", "", finish), finish
+	}
+	res, finish := login(authenticator)
+	signedIn(t, res)
+	if authenticator.counter != 1 || len(h.db.passkeys[0].Credential) == 0 {
+		t.Fatalf("signature counter not stored: %+v", h.db.passkeys[0])
+	}
+
+	// the assertion is not replayed
+	h.do(http.MethodPost, "/api/v1/auth/webauthn/login/finish", "", finish).status(http.StatusBadRequest)
+
+	// the signature of another key is refused
+	forged := newSoftAuthenticator(t)
+	forged.credentialID, forged.userHandle = authenticator.credentialID, authenticator.userHandle
+	res, _ = login(forged)
+	res.status(http.StatusUnauthorized)
+
+	// the signature counter going backwards points at a cloned authenticator
+	authenticator.counter = 0
+	res, _ = login(authenticator)
+	res.status(http.StatusUnauthorized)
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	h := newWebAuthnHarness(t)
+	authenticator := newSoftAuthenticator(t)
+	register(h, h.signin("alice@example.com", "Secret123"), authenticator).status(http.StatusCreated)
+	h.db.users["u1"].Enable2fa = true
+
+	challenge, methods := passwordChallenge(h, "alice@example.com", "Secret123")
+	if len(methods) != 2 || methods[1] != "webauthn" {
+		t.Fatalf("passkey not offered for the challenge: %v", methods)
+	}
+
+	options := &protocol.CredentialAssertion{}
+	state := webauthnBegin(h, "/api/v1/auth/webauthn/2fa/begin", "", map[string]string{"challenge": challenge}, options)
+	if len(options.Response.AllowedCredentials) != 1 {
+		t.Fatalf("passkeys of the user not allowed: %+v", options.Response.AllowedCredentials)
+	}
+	finish := map[string]any{"challenge": challenge, "state": state, "credential": authenticator.get(options)}
+	signedIn(t, h.do(http.MethodPost, "/api/v1/auth/webauthn/2fa/finish", "", finish))
+
+	// the challenge is single use
+	h.do(http.MethodPost, "/api/v1/auth/webauthn/2fa/begin", "", map[string]string{"challenge": challenge}).status(http.StatusUnauthorized)
+}
+
+func TestWebAuthnSecondFactorAttempts(t *testing.T) {
+	h := newWebAuthnHarness(t)
+	authenticator := newSoftAuthenticator(t)
+	register(h, h.signin("alice@example.com", "Secret123"), authenticator).status(http.StatusCreated)
+	h.db.users["u1"].Enable2fa = true
+
+	// the forged assertions end the ceremony at the attempt limit
+	challenge, _ := passwordChallenge(h, "alice@example.com", "Secret123")
+	forged := newSoftAuthenticator(t)
+	forged.credentialID, forged.userHandle = authenticator.credentialID, authenticator.userHandle
+	for range 5 {
+		options := &protocol.CredentialAssertion{}
+		state := webauthnBegin(h, "/api/v1/auth/webauthn/2fa/begin", "", map[string]string{"challenge": challenge}, options)
+		finish := map[string]any{"challenge": challenge, "state": state, "credential": forged.get(options)}
+		h.do(http.MethodPost, "/api/v1/auth/webauthn/2fa/finish", "", finish).status(http.StatusUnauthorized)
+	}
+	h.do(http.MethodPost, "/api/v1/auth/webauthn/2fa/begin", "", map[string]string{"challenge": challenge}).status(http.StatusUnauthorized)
+
+	// the challenge is outdated once the second factor is turned off
+	challenge, _ = passwordChallenge(h, "alice@example.com", "Secret123")
+	options := &protocol.CredentialAssertion{}
+	state := webauthnBegin(h, "/api/v1/auth/webauthn/2fa/begin", "", map[string]string{"challenge": challenge}, options)
+	h.db.users["u1"].Enable2fa = false
+	finish := map[string]any{"challenge": challenge, "state": state, "credential": authenticator.get(options)}
+	h.do(http.MethodPost, "/api/v1/auth/webauthn/2fa/finish", "", finish).status(http.StatusConflict)
+}
@@ -2,9 +2,15 @@ package auth
 
 import (
 	"context"
-	"log"
 
 	"github.com/urfave/cli/v3"
+
+	"brickwall/cmd/api"
+	"brickwall/internal/common"
+)
+
+var (
+	defServerAddress string = "0.0.0.0:8082"
 )
 
 func Command(ctx context.Context) *cli.Command {
@@ -13,14 +19,10 @@ func Command(ctx context.Context) *cli.Command {
 		Category: "services",
 		Usage:    "Run the auth service",
 		Action: func(ctx context.Context, cli *cli.Command) error {
-			return bootstrap(ctx, cli)
+			ctx = context.WithValue(ctx, common.KeyCommand, cli)
+			return api.Serve(ctx, RegisterRoutes)
 		},
+		Flags: api.Flags(defServerAddress),
 	}
 	return command
 }
-
-func bootstrap(_ context.Context, _ *cli.Command) error {
-	log.Println("DEBUG: Auth command executed")
-
-	return nil
-}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"context"
+
+	"brickwall/cmd/api/controller"
+	"brickwall/internal/provider"
+)
+
+// RegisterRoutes serves the signup, signin, refresh, second factor and token
+// issuance routes, the api and the other services only verify the tokens
+func RegisterRoutes(ctx context.Context, router provider.IRouterProvider) {
+	// Well-known documents are served from the root by convention
+	controller.NewWellKnownController(ctx, router.Engine().Group("/.well-known")).Register()
+
+	api := router.Engine().Group("/api")
+	{
+		v1 := api.Group("/v1")
+		{
+			controller.NewAuxController(ctx, v1).Register()
+			controller.NewAuthController(ctx, v1).Register()
+			controller.NewOAuthController(ctx, v1).Register()
+		}
+	}
+}
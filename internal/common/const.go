@@ -15,4 +15,9 @@ const (
 	KeyJwtProvider       KeyString = "key-jwt-provider"
 	Key2FAProvider       KeyString = "key-2fa-provider"
 	KeyPgxProvider       KeyString = "key-pgx-provider"
+	KeyMailerProvider    KeyString = "key-mailer-provider"
+	KeyOidcProvider      KeyString = "key-oidc-provider"
+	KeyWebAuthnProvider  KeyString = "key-webauthn-provider"
+	KeyPasswordPolicy    KeyString = "key-password-policy-provider"
+	KeyPasswordHasher    KeyString = "key-password-hasher-provider"
 )
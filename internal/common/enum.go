@@ -1 +1,34 @@
 package common
+
+// Roles seeded by the general_auth migration
+const (
+	RoleSys       = "SYS"
+	RoleAdmin     = "ADMIN"
+	RoleEditor    = "EDITOR"
+	RoleAuditor   = "AUDITOR"
+	RoleManager   = "MANAGER"
+	RoleReporter  = "REPORTER"
+	RoleFinancier = "FINANCIER"
+)
+
+// Permissions seeded by the auth_permission migration
+const (
+	PermUserRead        = "user:read"
+	PermUserWrite       = "user:write"
+	PermUserBlock       = "user:block"
+	PermUserDelete      = "user:delete"
+	PermRoleRead        = "role:read"
+	PermRoleWrite       = "role:write"
+	PermRoleAssign      = "role:assign"
+	PermPermissionRead  = "permission:read"
+	PermPermissionWrite = "permission:write"
+	PermCountryWrite    = "country:write"
+	PermCurrencyWrite   = "currency:write"
+	PermApiKeyRead      = "apikey:read"
+	PermApiKeyWrite     = "apikey:write"
+	PermOAuthRead       = "oauth:read"
+	PermOAuthWrite      = "oauth:write"
+	PermTokenIntrospect = "token:introspect"
+	PermSessionRead     = "session:read"
+	PermSessionRevoke   = "session:revoke"
+)
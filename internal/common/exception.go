@@ -2,7 +2,11 @@ package common
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -10,7 +14,8 @@ var (
 	ErrNotImplemented = errors.New("not implemented")
 
 	// Request layer errors
-	ErrReqBindJson = errors.New("failed to bind json")
+	ErrReqBindJson    = errors.New("failed to bind json")
+	ErrTrustedProxies = errors.New("invalid trusted proxies")
 
 	// Database layer errors
 	ErrDBConnPoolExhausted = errors.New("failed to acquire connection")
@@ -30,15 +35,57 @@ var (
 	ErrAuthGenerateTokens  = errors.New("failed to generate tokens")
 	ErrAuthUserBlocked     = errors.New("user blocked")
 	ErrAuthUserNotChecked  = errors.New("user not checked")
+	ErrAuthTooManyRequests = errors.New("too many requests")
+	ErrAuthLocked          = errors.New("too many failed sign-ins")
+	ErrAuthUnauthenticated = errors.New("unauthenticated")
+	ErrAuthForbidden       = errors.New("forbidden")
+	ErrAuthServiceUrl      = errors.New("invalid auth service url")
+	ErrAuthUnavailable     = errors.New("auth service unavailable")
 
 	// Auth JWT layer errors
 	ErrJwtTokenInvalid     = errors.New("invalid token")
 	ErrJwtTokenSigning     = errors.New("signing token")
 	ErrJwtTokenClaims      = errors.New("invalid claims")
 	ErrJwtTokenInvalidated = errors.New("invalidated token")
+	ErrJwtTokenType        = errors.New("unexpected token type")
+	ErrJwtTokenReused      = errors.New("reused token")
+	ErrJwtKeyring          = errors.New("failed to load keys")
 
 	// 2FA layer errors
-	Err2FAKeyGeneration = errors.New("failed to generate key")
+	Err2FAKeyGeneration  = errors.New("failed to generate key")
+	Err2FAInvalidCode    = errors.New("invalid 2fa code")
+	Err2FANotEnabled     = errors.New("2fa not enabled")
+	Err2FAAlreadyEnabled = errors.New("2fa already enabled")
+
+	// Mailer layer errors
+	ErrMailerTransport = errors.New("unsupported mail transport")
+	ErrMailerSend      = errors.New("failed to send mail")
+
+	// OAuth layer errors, the messages are the RFC 6749 error codes
+	ErrOAuthInvalidRequest       = errors.New("invalid_request")
+	ErrOAuthInvalidClient        = errors.New("invalid_client")
+	ErrOAuthInvalidGrant         = errors.New("invalid_grant")
+	ErrOAuthUnauthorizedClient   = errors.New("unauthorized_client")
+	ErrOAuthUnsupportedGrantType = errors.New("unsupported_grant_type")
+	ErrOAuthInvalidScope         = errors.New("invalid_scope")
+	ErrOAuthAccessDenied         = errors.New("access_denied")
+
+	// OIDC layer errors
+	ErrOidcConfig   = errors.New("invalid identity provider config")
+	ErrOidcProvider = errors.New("unknown identity provider")
+	ErrOidcDiscover = errors.New("identity provider unavailable")
+	ErrOidcIdentity = errors.New("failed to verify identity")
+
+	// WebAuthn layer errors
+	ErrWebAuthnConfig     = errors.New("invalid webauthn config")
+	ErrWebAuthnCeremony   = errors.New("unknown or expired webauthn ceremony")
+	ErrWebAuthnCredential = errors.New("failed to verify webauthn credential")
+
+	// Password policy errors
+	ErrPasswordPolicy       = errors.New("password does not meet the policy")
+	ErrPasswordPolicyConfig = errors.New("invalid password policy config")
+	ErrPasswordHash         = errors.New("failed to hash password")
+	ErrPasswordHasherConfig = errors.New("invalid password hasher config")
 
 	// Business layer errors
 
@@ -47,10 +94,54 @@ var (
 	// Other layer errors
 )
 
+// RetryError tells the client when the rejected request may be retried
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (rcv *RetryError) Error() string {
+	return rcv.Err.Error()
+}
+
+func (rcv *RetryError) Unwrap() error {
+	return rcv.Err
+}
+
+// RetryAfter returns the Retry-After header value in seconds, if the error carries one
+func RetryAfter(err error) (string, bool) {
+	var retry *RetryError
+	if !errors.As(err, &retry) {
+		return "", false
+	}
+	return strconv.Itoa(int(math.Ceil(retry.RetryAfter.Seconds()))), true
+}
+
+// ValidationError carries the field level reasons of the rejected request
+type ValidationError struct {
+	Err     error
+	Details []*ExceptionDetail
+}
+
+func (rcv *ValidationError) Error() string {
+	return rcv.Err.Error()
+}
+
+func (rcv *ValidationError) Unwrap() error {
+	return rcv.Err
+}
+
+type ExceptionDetail struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type Exception struct {
-	Code      int    `json:"code"`
-	Message   string `json:"message"`
-	TimeStamp string `json:"timestamp"`
+	Code      int                `json:"code"`
+	Message   string             `json:"message"`
+	Details   []*ExceptionDetail `json:"details,omitempty"`
+	TimeStamp string             `json:"timestamp"`
 }
 
 func NewException(code int, message string) *Exception {
@@ -61,6 +152,15 @@ func NewException(code int, message string) *Exception {
 	}
 }
 
+// WithDetails attaches the field level reasons of the validation error, if any
+func (rcv *Exception) WithDetails(err error) *Exception {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		rcv.Details = validation.Details
+	}
+	return rcv
+}
+
 func ErrMapper(err error) (int, *Exception) {
 	switch {
 	case errors.Is(err, ErrReqBindJson):
@@ -95,6 +195,16 @@ func ErrMapper(err error) (int, *Exception) {
 		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
 	case errors.Is(err, ErrAuthUserNotChecked):
 		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrAuthTooManyRequests):
+		return http.StatusTooManyRequests, NewException(http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, ErrAuthLocked):
+		return http.StatusTooManyRequests, NewException(http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, ErrAuthUnauthenticated):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrAuthForbidden):
+		return http.StatusForbidden, NewException(http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrAuthUnavailable):
+		return http.StatusBadGateway, NewException(http.StatusBadGateway, err.Error())
 
 	case errors.Is(err, ErrJwtTokenInvalid):
 		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
@@ -104,10 +214,70 @@ func ErrMapper(err error) (int, *Exception) {
 		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
 	case errors.Is(err, ErrJwtTokenClaims):
 		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrJwtTokenType):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrJwtTokenReused):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
 
 	case errors.Is(err, Err2FAKeyGeneration):
 		return http.StatusExpectationFailed, NewException(http.StatusExpectationFailed, err.Error())
+	case errors.Is(err, Err2FAInvalidCode):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, Err2FANotEnabled):
+		return http.StatusConflict, NewException(http.StatusConflict, err.Error())
+	case errors.Is(err, Err2FAAlreadyEnabled):
+		return http.StatusConflict, NewException(http.StatusConflict, err.Error())
+
+	case errors.Is(err, ErrOidcProvider):
+		return http.StatusNotFound, NewException(http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrOidcDiscover):
+		return http.StatusBadGateway, NewException(http.StatusBadGateway, err.Error())
+	case errors.Is(err, ErrOidcIdentity):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+
+	case errors.Is(err, ErrWebAuthnCeremony):
+		return http.StatusBadRequest, NewException(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrWebAuthnCredential):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+
+	case errors.Is(err, ErrPasswordPolicy):
+		return http.StatusBadRequest, NewException(http.StatusBadRequest, err.Error()).WithDetails(err)
+	case errors.Is(err, ErrPasswordHash):
+		return http.StatusInternalServerError, NewException(http.StatusInternalServerError, err.Error())
+
+	case errors.Is(err, ErrOAuthInvalidClient):
+		return http.StatusUnauthorized, NewException(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ErrOAuthAccessDenied):
+		return http.StatusForbidden, NewException(http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrOAuthInvalidRequest), errors.Is(err, ErrOAuthInvalidGrant),
+		errors.Is(err, ErrOAuthUnauthorizedClient), errors.Is(err, ErrOAuthUnsupportedGrantType),
+		errors.Is(err, ErrOAuthInvalidScope):
+		return http.StatusBadRequest, NewException(http.StatusBadRequest, err.Error())
 	default:
 		return http.StatusInternalServerError, NewException(http.StatusInternalServerError, err.Error())
 	}
 }
+
+// OAuthException is the RFC 6749 error response of the token endpoint
+type OAuthException struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthErrMapper maps the error to the RFC 6749 error response, errors outside
+// of the OAuth layer are reported as server_error
+func OAuthErrMapper(err error) (int, *OAuthException) {
+	for _, oauthErr := range []error{
+		ErrOAuthInvalidRequest, ErrOAuthInvalidClient, ErrOAuthInvalidGrant, ErrOAuthUnauthorizedClient,
+		ErrOAuthUnsupportedGrantType, ErrOAuthInvalidScope, ErrOAuthAccessDenied,
+	} {
+		if errors.Is(err, oauthErr) {
+			code, _ := ErrMapper(err)
+			return code, &OAuthException{
+				Error:            oauthErr.Error(),
+				ErrorDescription: strings.TrimPrefix(err.Error(), oauthErr.Error()+": "),
+			}
+		}
+	}
+	return http.StatusInternalServerError, &OAuthException{Error: "server_error", ErrorDescription: err.Error()}
+}
@@ -1,6 +1,10 @@
 package common
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"slices"
 	"time"
 )
@@ -12,3 +16,27 @@ func TimestampISO3339NS() string {
 func Contains[T comparable](slice []T, value T) bool {
 	return slices.Contains(slice, value)
 }
+
+// RandomToken returns url safe random token of the given size in bytes
+func RandomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the stored form of an opaque token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Truncate cuts the string to the given number of characters
+func Truncate(value string, size int) string {
+	runes := []rune(value)
+	if len(runes) <= size {
+		return value
+	}
+	return string(runes[:size])
+}
@@ -0,0 +1,50 @@
+package common
+
+// Principal is the authenticated user as loaded by the auth middleware
+type Principal struct {
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	IsBlocked bool     `json:"is_blocked"`
+	IsChecked bool     `json:"is_checked"`
+	Roles     []string `json:"roles"`
+
+	// Permissions are resolved from the roles on every request and never cached with the principal
+	Permissions []string `json:"-"`
+	// Scopes narrow the permissions when the request is authenticated by an api key
+	Scopes []string `json:"-"`
+}
+
+// HasRole reports whether any of the given roles is assigned to the principal
+func (rcv *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if Contains(rcv.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Owns reports whether the resource owner is the principal itself
+func (rcv *Principal) Owns(ownerID string) bool {
+	return ownerID != "" && rcv.UserID == ownerID
+}
+
+// InScope reports whether the api key or client token scopes grant the permission,
+// principals authenticated by the user session are not scoped
+func (rcv *Principal) InScope(permission string) bool {
+	return rcv.Scopes == nil || Contains(rcv.Scopes, permission)
+}
+
+// HasPermission reports whether all of the given permissions are granted to the principal,
+// SYS is granted every permission but still limited by the api key scopes
+func (rcv *Principal) HasPermission(permissions ...string) bool {
+	for _, permission := range permissions {
+		if !rcv.InScope(permission) {
+			return false
+		}
+		if !rcv.HasRole(RoleSys) && !Contains(rcv.Permissions, permission) {
+			return false
+		}
+	}
+	return true
+}
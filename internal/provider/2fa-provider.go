@@ -1,8 +1,16 @@
 package provider
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
 	"fmt"
+	"image/png"
+	"strings"
 
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 
 	"brickwall/internal/common"
@@ -10,9 +18,18 @@ import (
 
 const Issuer = "BSP"
 
+const (
+	qrCodeSize = 256
+
+	RecoveryCodesNum = 10
+)
+
 type I2FAProvider interface {
 	GenerateSecretKey(string) (string, string, error)
+	GenerateQRCode(string) ([]byte, error)
 	VerifyCode(string, string) bool
+	GenerateRecoveryCodes() ([]string, error)
+	HashRecoveryCode(string) string
 }
 
 type TwoFAProvider struct{}
@@ -32,6 +49,45 @@ func (rcv *TwoFAProvider) GenerateSecretKey(userEmail string) (string, string, e
 	return key.Secret(), key.URL(), nil
 }
 
+// GenerateQRCode renders the otpauth url as a PNG image for authenticator apps
+func (rcv *TwoFAProvider) GenerateQRCode(url string) ([]byte, error) {
+	key, err := otp.NewKeyFromURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAKeyGeneration, err)
+	}
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAKeyGeneration, err)
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.Err2FAKeyGeneration, err)
+	}
+	return buf.Bytes(), nil
+}
+
 func (s *TwoFAProvider) VerifyCode(secret, code string) bool {
 	return totp.Validate(code, secret)
 }
+
+// GenerateRecoveryCodes returns single-use codes formatted as xxxxx-xxxxx
+func (rcv *TwoFAProvider) GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, RecoveryCodesNum)
+
+	for range RecoveryCodesNum {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.Err2FAKeyGeneration, err)
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode normalizes the user input and returns the stored form of the code
+func (rcv *TwoFAProvider) HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,384 @@
+package provider
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRetryBackoff spaces the fetches while the auth service is unreachable
+const jwksRetryBackoff = 5 * time.Second
+
+// Jwk is the public part of a signing key as described by RFC 7517
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type Jwks struct {
+	Keys []*Jwk `json:"keys"`
+}
+
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// JwtKeyring keeps the active signing key and every key accepted for verification.
+// Keeping the previous public keys around lets tokens survive the key rotation.
+type JwtKeyring struct {
+	signKid    string
+	signMethod jwt.SigningMethod
+	signKey    any
+
+	mu     sync.RWMutex
+	verify map[string]*jwtKey
+	kids   []string
+
+	// remote keyrings hold no signing key, the verification keys are fetched
+	// from the auth service again when a token names an unknown kid
+	jwksUrl   string
+	client    *http.Client
+	interval  time.Duration
+	fetchMu   sync.Mutex
+	fetchedAt time.Time
+	failedAt  time.Time
+}
+
+// NewJwtKeyring loads the private signing key and the additional public keys.
+// When no private key is given the keyring falls back to the HS256 shared secret.
+func NewJwtKeyring(privateKeyFile string, publicKeyFiles []string, secret string) (*JwtKeyring, error) {
+	keyring := &JwtKeyring{verify: map[string]*jwtKey{}}
+
+	if privateKeyFile == "" {
+		if secret == "" {
+			return nil, errors.New("neither jwt private key nor jwt secret configured")
+		}
+		keyring.signMethod = jwt.SigningMethodHS256
+		keyring.signKey = []byte(secret)
+		return keyring, nil
+	}
+	signer, err := readPrivateKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newJwtKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	keyring.signKid = key.kid
+	keyring.signMethod = key.method
+	keyring.signKey = signer
+	keyring.add(key)
+
+	for _, file := range publicKeyFiles {
+		public, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newJwtKey(public)
+		if err != nil {
+			return nil, err
+		}
+		keyring.add(key)
+	}
+	return keyring, nil
+}
+
+// NewRemoteJwtKeyring trusts the keys published by the auth service at the jwks
+// url, after a failed first fetch the keys are fetched again by the first token
+// to verify once the retry backoff has passed
+func NewRemoteJwtKeyring(jwksUrl string, interval time.Duration) *JwtKeyring {
+	keyring := &JwtKeyring{
+		verify:   map[string]*jwtKey{},
+		jwksUrl:  jwksUrl,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		interval: interval,
+	}
+	if err := keyring.refresh(); err != nil {
+		slog.Warn("jwks fetch failed", "url", jwksUrl, "error", err.Error())
+	}
+	return keyring
+}
+
+// Sign signs the token with the active key and stamps its kid header
+func (rcv *JwtKeyring) Sign(claims jwt.Claims) (string, error) {
+	if rcv.signKey == nil {
+		return "", errors.New("verify only keyring, tokens are issued by the auth service")
+	}
+	token := jwt.NewWithClaims(rcv.signMethod, claims)
+	if rcv.signKid != "" {
+		token.Header["kid"] = rcv.signKid
+	}
+	return token.SignedString(rcv.signKey)
+}
+
+// Keyfunc resolves the verification key by the kid header of the token
+func (rcv *JwtKeyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if rcv.signMethod == jwt.SigningMethodHS256 {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+		}
+		return rcv.signKey, nil
+	}
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("missing kid header")
+	}
+	key, ok := rcv.key(kid)
+	if !ok && rcv.jwksUrl != "" {
+		if err := rcv.refresh(); err != nil {
+			slog.Warn("jwks fetch failed", "url", rcv.jwksUrl, "error", err.Error())
+		}
+		key, ok = rcv.key(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// Jwks publishes the verification keys, the shared secret is never exposed
+func (rcv *JwtKeyring) Jwks() *Jwks {
+	rcv.mu.RLock()
+	defer rcv.mu.RUnlock()
+
+	jwks := &Jwks{Keys: []*Jwk{}}
+	for _, kid := range rcv.kids {
+		jwks.Keys = append(jwks.Keys, rcv.verify[kid].jwk())
+	}
+	return jwks
+}
+
+func (rcv *JwtKeyring) key(kid string) (*jwtKey, bool) {
+	rcv.mu.RLock()
+	defer rcv.mu.RUnlock()
+
+	key, ok := rcv.verify[kid]
+	return key, ok
+}
+
+// refresh replaces the verification keys by the published ones, the fetches are
+// throttled so tokens with forged kids cannot flood the auth service
+func (rcv *JwtKeyring) refresh() error {
+	rcv.fetchMu.Lock()
+	defer rcv.fetchMu.Unlock()
+
+	if time.Since(rcv.fetchedAt) < rcv.interval || time.Since(rcv.failedAt) < jwksRetryBackoff {
+		return nil
+	}
+	verify, kids, err := rcv.fetch()
+	if err != nil {
+		rcv.failedAt = time.Now()
+		return err
+	}
+	rcv.fetchedAt = time.Now()
+
+	rcv.mu.Lock()
+	rcv.verify, rcv.kids = verify, kids
+	rcv.mu.Unlock()
+	return nil
+}
+
+// fetch downloads and decodes the published keys
+func (rcv *JwtKeyring) fetch() (map[string]*jwtKey, []string, error) {
+	res, err := rcv.client.Get(rcv.jwksUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	jwks := &Jwks{}
+	if err := json.NewDecoder(res.Body).Decode(jwks); err != nil {
+		return nil, nil, err
+	}
+	verify, kids := map[string]*jwtKey{}, []string{}
+	for _, jwk := range jwks.Keys {
+		public, err := jwk.public()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := newJwtKey(public)
+		if err != nil {
+			return nil, nil, err
+		}
+		key.kid = jwk.Kid
+		verify[key.kid] = key
+		kids = append(kids, key.kid)
+	}
+	return verify, kids, nil
+}
+
+func (rcv *JwtKeyring) add(key *jwtKey) {
+	if _, ok := rcv.verify[key.kid]; !ok {
+		rcv.kids = append(rcv.kids, key.kid)
+	}
+	rcv.verify[key.kid] = key
+}
+
+func newJwtKey(public crypto.PublicKey) (*jwtKey, error) {
+	key := &jwtKey{public: public}
+
+	switch public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type: %T", public)
+	}
+	kid, err := key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	key.kid = kid
+	return key, nil
+}
+
+// public decodes the published key
+func (rcv *Jwk) public() (crypto.PublicKey, error) {
+	switch rcv.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(rcv.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(rcv.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(rcv.X)
+		if err != nil {
+			return nil, err
+		}
+		if rcv.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported okp key: %s", rcv.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", rcv.Kty)
+	}
+}
+
+func (rcv *jwtKey) jwk() *Jwk {
+	jwk := &Jwk{Kid: rcv.kid, Use: "sig", Alg: rcv.method.Alg()}
+
+	switch public := rcv.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 key thumbprint used as kid
+func (rcv *jwtKey) thumbprint() (string, error) {
+	var members any
+
+	jwk := rcv.jwk()
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	raw, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no pem data found in %s", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key", file)
+	}
+	return signer, nil
+}
+
+// readPublicKey accepts public keys as well as private keys of retired signers
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		signer, err := readPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+}
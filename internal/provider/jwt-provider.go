@@ -2,7 +2,10 @@ package provider
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,26 +18,59 @@ import (
 const (
 	TokenValid   = "valid"
 	TokenInvalid = "invalid"
+
+	TokenTypeAccess    = "access"
+	TokenTypeRefresh   = "refresh"
+	TokenTypeChallenge = "challenge"
+	TokenTypeVerify    = "verify"
+
+	keyRevokedToken = "jwt:revoked:"
+	keyRefreshToken = "jwt:refresh:"
+	keyTokenFamily  = "jwt:family:"
+	keyUserFamilies = "jwt:families:"
 )
 
 type IJwtProvider interface {
+	LoadKeys() error
+	Jwks() *Jwks
 	GenerateTokens(string) (string, string, error)
+	GenerateToken(string, string, time.Duration) (string, error)
+	GenerateClientTokens(string, string, string) (string, string, error)
+	GenerateClientToken(string, string, string) (string, error)
 	RefreshTokens(string) (string, string, error)
 	ValidateToken(string) (*Claims, error)
 	InvalidateToken(string) error
+	InvalidateUserTokens(string) error
+	InvalidateUserTokensExcept(string, string) error
+	InvalidateFamily(string, string) error
 	IsTokenInvalidated(string) bool
+	IntrospectToken(string) (*Claims, error)
 	StoreToken(string) error
 }
 
+// Claims carries the registered claims (sub, iss, aud, iat, nbf, exp, jti)
+// together with the token type and the refresh token family it belongs to.
+// Tokens issued to OAuth clients carry the client id and the granted scope.
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID string `json:"user_id"`
+	Type     string `json:"typ"`
+	FamilyID string `json:"fid"`
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// UserID returns the subject the token was issued to
+func (rcv *Claims) UserID() string {
+	return rcv.Subject
 }
 
 type JwtProvider struct {
 	ctx               context.Context
 	redis             *redis.Client
-	secret            string
+	keyring           *JwtKeyring
+	parser            *jwt.Parser
+	issuer            string
+	audience          string
 	accessExpiration  time.Duration
 	refreshExpiration time.Duration
 }
@@ -46,78 +82,306 @@ func NewJwtProvider(ctx context.Context) IJwtProvider {
 	return &JwtProvider{
 		ctx:               ctx,
 		redis:             redis.Client(),
-		secret:            cli.String("jwt-secret"),
+		issuer:            cli.String("jwt-issuer"),
+		audience:          cli.String("jwt-audience"),
 		accessExpiration:  cli.Duration("jwt-access-expiration"),
 		refreshExpiration: cli.Duration("jwt-refresh-expiration"),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(cli.StringSlice("jwt-allowed-algorithms")),
+			jwt.WithIssuer(cli.String("jwt-issuer")),
+			jwt.WithAudience(cli.String("jwt-audience")),
+			jwt.WithLeeway(cli.Duration("jwt-leeway")),
+			jwt.WithIssuedAt(),
+			jwt.WithExpirationRequired(),
+		),
 	}
 }
 
+// LoadKeys reads the signing and verification keys configured for the service
+func (rcv *JwtProvider) LoadKeys() error {
+	cli := rcv.ctx.Value(common.KeyCommand).(*cli.Command)
+
+	// verify only services trust the keys published by the auth service
+	if jwksUrl := cli.String("jwt-jwks-url"); jwksUrl != "" {
+		rcv.keyring = NewRemoteJwtKeyring(jwksUrl, cli.Duration("jwt-jwks-refresh-interval"))
+		return nil
+	}
+	keyring, err := NewJwtKeyring(
+		cli.String("jwt-private-key"), cli.StringSlice("jwt-public-keys"), cli.String("jwt-secret"),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrJwtKeyring, err)
+	}
+	if alg := keyring.signMethod.Alg(); !slices.Contains(cli.StringSlice("jwt-allowed-algorithms"), alg) {
+		return fmt.Errorf("%w: signing algorithm %s is not allowed", common.ErrJwtKeyring, alg)
+	}
+	rcv.keyring = keyring
+	return nil
+}
+
+func (rcv *JwtProvider) Jwks() *Jwks {
+	return rcv.keyring.Jwks()
+}
+
+// GenerateTokens issues a new access/refresh pair which starts a new token family
 func (rcv *JwtProvider) GenerateTokens(userID string) (string, string, error) {
-	AccessExpiration := time.Now().Add(rcv.accessExpiration)
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(AccessExpiration),
-		},
-	})
-	signedAccessToken, err := accessToken.SignedString([]byte(rcv.secret))
+	familyID, err := randomID()
 	if err != nil {
 		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
 	}
-	refreshExpiration := time.Now().Add(rcv.refreshExpiration)
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(refreshExpiration),
-	})
-	signedRefreshToken, err := refreshToken.SignedString([]byte(rcv.secret))
+	return rcv.generateTokens(userID, familyID, "", "")
+}
+
+// GenerateToken issues a standalone short-lived token of the given type
+// (e.g. the sign-in challenge), it does not belong to any token family
+func (rcv *JwtProvider) GenerateToken(userID, tokenType string, expiration time.Duration) (string, error) {
+	token, err := rcv.sign(userID, "", tokenType, "", "", expiration)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
+	}
+	return token, nil
+}
+
+// GenerateClientTokens issues an access/refresh pair on behalf of the user to the
+// OAuth client, the client and scope are kept across refresh rotations
+func (rcv *JwtProvider) GenerateClientTokens(userID, clientID, scope string) (string, string, error) {
+	familyID, err := randomID()
 	if err != nil {
 		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
 	}
-	return signedAccessToken, signedRefreshToken, nil
+	return rcv.generateTokens(userID, familyID, clientID, scope)
 }
 
+// GenerateClientToken issues a standalone access token to the OAuth client
+// acting on its own behalf, there is no refresh token for such grants
+func (rcv *JwtProvider) GenerateClientToken(userID, clientID, scope string) (string, error) {
+	token, err := rcv.sign(userID, "", TokenTypeAccess, clientID, scope, rcv.accessExpiration)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
+	}
+	return token, nil
+}
+
+// RefreshTokens rotates the refresh token within its family. A refresh token
+// which was already rotated revokes the whole family as soon as it is replayed.
 func (rcv *JwtProvider) RefreshTokens(tokenString string) (string, string, error) {
 	claims, err := rcv.ValidateToken(tokenString)
 	if err != nil {
 		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
 	}
-	return rcv.GenerateTokens(claims.UserID)
+	if claims.Type != TokenTypeRefresh {
+		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	deleted, err := rcv.redis.Del(context.Background(), keyRefreshToken+claims.ID).Result()
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenInvalid, err)
+	}
+	if deleted == 0 {
+		if err := rcv.revokeFamily(claims.FamilyID); err != nil {
+			return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenReused, err)
+		}
+		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenReused, "token family revoked")
+	}
+	access, refresh, err := rcv.generateTokens(claims.UserID(), claims.FamilyID, claims.ClientID, claims.Scope)
+	if err != nil {
+		return "", "", err
+	}
+	if err := rcv.StoreToken(refresh); err != nil {
+		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
+	}
+	return access, refresh, nil
 }
 
 func (rcv *JwtProvider) ValidateToken(tokenString string) (*Claims, error) {
-	val, err := rcv.redis.Get(context.Background(), tokenString).Result()
-	if err == nil && val == TokenInvalid {
-		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalidated, "marked as invalid")
-	}
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return rcv.secret, nil
-	})
+	token, err := rcv.parser.ParseWithClaims(tokenString, &Claims{}, rcv.keyring.Keyfunc)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, "failed to parse claims")
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, err)
 	}
 	claims, ok := token.Claims.(*Claims)
 	if !ok || !token.Valid {
 		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, "failed to bind claims")
 	}
+	if claims.ID == "" || claims.Subject == "" || claims.Type == "" {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, "missing required claims")
+	}
+	if rcv.IsTokenInvalidated(claims.ID) {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalidated, "marked as invalid")
+	}
+	if rcv.isFamilyRevoked(claims.FamilyID) {
+		return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalidated, "token family revoked")
+	}
 	return claims, nil
 }
 
+// InvalidateToken marks the token jti revoked until the moment it expires anyway
 func (rcv *JwtProvider) InvalidateToken(tokenString string) error {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, rcv.keyring.Keyfunc, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, "failed to parse claims")
+	}
+	if claims.ExpiresAt == nil || claims.ID == "" {
+		return fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, "missing expiration or jti")
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
 	ctx := context.Background()
-	return rcv.redis.Set(ctx, tokenString, TokenInvalid, rcv.accessExpiration).Err()
+	if claims.Type == TokenTypeRefresh {
+		if err := rcv.redis.Del(ctx, keyRefreshToken+claims.ID).Err(); err != nil {
+			return err
+		}
+	}
+	return rcv.redis.Set(ctx, keyRevokedToken+claims.ID, TokenInvalid, ttl).Err()
 }
 
-func (rcv *JwtProvider) IsTokenInvalidated(tokenString string) bool {
+// InvalidateUserTokens revokes every token family issued to the user
+func (rcv *JwtProvider) InvalidateUserTokens(userID string) error {
 	ctx := context.Background()
-	val, err := rcv.redis.Get(ctx, tokenString).Result()
+
+	families, err := rcv.redis.SMembers(ctx, keyUserFamilies+userID).Result()
+	if err != nil {
+		return err
+	}
+	for _, familyID := range families {
+		if err := rcv.revokeFamily(familyID); err != nil {
+			return err
+		}
+	}
+	return rcv.redis.Del(ctx, keyUserFamilies+userID).Err()
+}
+
+// InvalidateUserTokensExcept revokes every token family of the user but the given one
+func (rcv *JwtProvider) InvalidateUserTokensExcept(userID, familyID string) error {
+	ctx := context.Background()
+
+	families, err := rcv.redis.SMembers(ctx, keyUserFamilies+userID).Result()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if family == familyID {
+			continue
+		}
+		if err := rcv.revokeFamily(family); err != nil {
+			return err
+		}
+		if err := rcv.redis.SRem(ctx, keyUserFamilies+userID, family).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateFamily revokes the single token family of the user, i.e. one signed-in device
+func (rcv *JwtProvider) InvalidateFamily(userID, familyID string) error {
+	if err := rcv.revokeFamily(familyID); err != nil {
+		return err
+	}
+	return rcv.redis.SRem(context.Background(), keyUserFamilies+userID, familyID).Err()
+}
+
+// IsTokenInvalidated checks the revocation list for the token jti
+func (rcv *JwtProvider) IsTokenInvalidated(jti string) bool {
+	ctx := context.Background()
+	val, err := rcv.redis.Get(ctx, keyRevokedToken+jti).Result()
 	return err == nil && val == TokenInvalid
 }
 
+// IntrospectToken validates the token for the introspection endpoint, on top of
+// the revocation list checked by ValidateToken a refresh token is active only
+// until it is rotated
+func (rcv *JwtProvider) IntrospectToken(tokenString string) (*Claims, error) {
+	claims, err := rcv.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type == TokenTypeRefresh {
+		live, err := rcv.redis.Exists(context.Background(), keyRefreshToken+claims.ID).Result()
+		if err != nil {
+			return nil, err
+		}
+		if live == 0 {
+			return nil, fmt.Errorf("%w: %v", common.ErrJwtTokenInvalidated, "refresh token rotated")
+		}
+	}
+	return claims, nil
+}
+
+// StoreToken registers the refresh token as the only live member of its family
 func (rcv *JwtProvider) StoreToken(tokenString string) error {
+	claims := &Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return fmt.Errorf("%w: %v", common.ErrJwtTokenClaims, err)
+	}
+	if claims.Type != TokenTypeRefresh {
+		return fmt.Errorf("%w: %v", common.ErrJwtTokenType, claims.Type)
+	}
+	ctx := context.Background()
+	if err := rcv.redis.Set(ctx, keyRefreshToken+claims.ID, claims.FamilyID, rcv.refreshExpiration).Err(); err != nil {
+		return err
+	}
+	// remember the family for the user wide revocation
+	if err := rcv.redis.SAdd(ctx, keyUserFamilies+claims.UserID(), claims.FamilyID).Err(); err != nil {
+		return err
+	}
+	return rcv.redis.Expire(ctx, keyUserFamilies+claims.UserID(), rcv.refreshExpiration).Err()
+}
+
+func (rcv *JwtProvider) generateTokens(userID, familyID, clientID, scope string) (string, string, error) {
+	signedAccessToken, err := rcv.sign(userID, familyID, TokenTypeAccess, clientID, scope, rcv.accessExpiration)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
+	}
+	signedRefreshToken, err := rcv.sign(userID, familyID, TokenTypeRefresh, clientID, scope, rcv.refreshExpiration)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", common.ErrJwtTokenSigning, err)
+	}
+	return signedAccessToken, signedRefreshToken, nil
+}
+
+func (rcv *JwtProvider) sign(userID, familyID, tokenType, clientID, scope string, expiration time.Duration) (string, error) {
+	jti, err := randomID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return rcv.keyring.Sign(Claims{
+		Type:     tokenType,
+		FamilyID: familyID,
+		ClientID: clientID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   userID,
+			Issuer:    rcv.issuer,
+			Audience:  jwt.ClaimStrings{rcv.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+		},
+	})
+}
+
+func (rcv *JwtProvider) revokeFamily(familyID string) error {
 	ctx := context.Background()
-	return rcv.redis.Set(ctx, tokenString, TokenValid, rcv.accessExpiration).Err()
+	return rcv.redis.Set(ctx, keyTokenFamily+familyID, TokenInvalid, rcv.refreshExpiration).Err()
 }
 
-func (rcv *JwtProvider) DeleteToken(tokenString string) error {
+func (rcv *JwtProvider) isFamilyRevoked(familyID string) bool {
+	if familyID == "" {
+		return false
+	}
 	ctx := context.Background()
-	return rcv.redis.Del(ctx, tokenString).Err()
+	val, err := rcv.redis.Get(ctx, keyTokenFamily+familyID).Result()
+	return err == nil && val == TokenInvalid
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
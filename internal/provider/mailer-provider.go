@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"brickwall/internal/common"
+)
+
+const (
+	MailerTransportLog  = "log"
+	MailerTransportFile = "file"
+	MailerTransportSmtp = "smtp"
+)
+
+type Mail struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+type IMailerProvider interface {
+	Send(*Mail) error
+}
+
+// IMailTransport delivers the prepared mail, transports are picked by the --mailer-transport flag
+type IMailTransport interface {
+	Deliver(string, *Mail) error
+}
+
+type MailerProvider struct {
+	ctx       context.Context
+	from      string
+	transport IMailTransport
+}
+
+func NewMailerProvider(ctx context.Context) (IMailerProvider, error) {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	var transport IMailTransport
+
+	switch cli.String("mailer-transport") {
+	case MailerTransportLog:
+		transport = &LogMailTransport{}
+	case MailerTransportFile:
+		transport = &FileMailTransport{dir: cli.String("mailer-file-dir")}
+	case MailerTransportSmtp:
+		transport = &SmtpMailTransport{
+			addr:     cli.String("mailer-smtp-addr"),
+			username: cli.String("mailer-smtp-username"),
+			password: cli.String("mailer-smtp-password"),
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown transport %s", common.ErrMailerTransport, cli.String("mailer-transport"))
+	}
+	return &MailerProvider{
+		ctx:       ctx,
+		from:      cli.String("mailer-from"),
+		transport: transport,
+	}, nil
+}
+
+func (rcv *MailerProvider) Send(mail *Mail) error {
+	if len(mail.To) == 0 {
+		return fmt.Errorf("%w: %v", common.ErrMailerSend, "no recipients")
+	}
+	if err := rcv.transport.Deliver(rcv.from, mail); err != nil {
+		return fmt.Errorf("%w: %v", common.ErrMailerSend, err)
+	}
+	return nil
+}
+
+// LogMailTransport writes the mail into the service log, handy for local development
+type LogMailTransport struct{}
+
+func (rcv *LogMailTransport) Deliver(from string, mail *Mail) error {
+	slog.Info("mail", "from", from, "to", strings.Join(mail.To, ","), "subject", mail.Subject, "body", mail.Body)
+	return nil
+}
+
+// FileMailTransport stores every mail as an .eml file in the configured directory
+type FileMailTransport struct {
+	dir string
+}
+
+func (rcv *FileMailTransport) Deliver(from string, mail *Mail) error {
+	if err := os.MkdirAll(rcv.dir, 0o755); err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(rcv.dir, name), mail.message(from), 0o644)
+}
+
+// SmtpMailTransport delivers through the SMTP relay, STARTTLS is used when the server offers it
+type SmtpMailTransport struct {
+	addr     string
+	username string
+	password string
+}
+
+func (rcv *SmtpMailTransport) Deliver(from string, mail *Mail) error {
+	sender, err := mail.sender(from)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if rcv.username != "" {
+		host, _, err := net.SplitHostPort(rcv.addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", rcv.username, rcv.password, host)
+	}
+	return smtp.SendMail(rcv.addr, auth, sender, mail.To, mail.message(from))
+}
+
+func (rcv *Mail) sender(from string) (string, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", err
+	}
+	return address.Address, nil
+}
+
+// message renders the plain text RFC 5322 message
+func (rcv *Mail) message(from string) []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(rcv.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", rcv.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(buf, "Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(rcv.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
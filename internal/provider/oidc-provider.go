@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/oauth2"
+
+	"brickwall/internal/common"
+)
+
+// OidcProviderConfig describes an external OpenID Connect provider, providers
+// come from the --oidc-config file and from the single provider --oidc-* flags
+type OidcProviderConfig struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+	RedirectUrl  string   `json:"redirect_url"`
+
+	// AllowedDomains limits the sign-in to the email domains, empty allows any
+	AllowedDomains []string `json:"allowed_domains"`
+	// TrustEmail treats the email as verified when the provider does not assert it
+	TrustEmail bool `json:"trust_email"`
+	// LinkByEmail links the identity to the local user with the same email on
+	// the first sign-in instead of refusing it
+	LinkByEmail bool `json:"link_by_email"`
+}
+
+type oidcConfigFile struct {
+	Providers []*OidcProviderConfig `json:"providers"`
+}
+
+// OidcIdentity is the verified ID token of the provider
+type OidcIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Firstname     string
+	Lastname      string
+}
+
+type IOidcProvider interface {
+	Providers() []*OidcProviderConfig
+	Provider(string) (*OidcProviderConfig, error)
+	AuthCodeUrl(name, state, nonce, verifier string) (string, error)
+	Exchange(name, code, verifier, nonce string) (*OidcIdentity, error)
+}
+
+// oidcIssuer is the discovered provider, discovery happens on first use so the
+// api starts while a provider is unreachable
+type oidcIssuer struct {
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+type OidcProvider struct {
+	ctx     context.Context
+	client  *http.Client
+	configs []*OidcProviderConfig
+
+	mu      sync.Mutex
+	issuers map[string]*oidcIssuer
+}
+
+func NewOidcProvider(ctx context.Context) (IOidcProvider, error) {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	configs := []*OidcProviderConfig{}
+	if path := cli.String("oidc-config"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrOidcConfig, err)
+		}
+		file := &oidcConfigFile{}
+		if err := json.Unmarshal(data, file); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrOidcConfig, err)
+		}
+		configs = append(configs, file.Providers...)
+	}
+	if issuer := cli.String("oidc-issuer"); issuer != "" {
+		configs = append(configs, &OidcProviderConfig{
+			Name:         cli.String("oidc-name"),
+			Issuer:       issuer,
+			ClientID:     cli.String("oidc-client-id"),
+			ClientSecret: cli.String("oidc-client-secret"),
+		})
+	}
+	names := []string{}
+	for _, config := range configs {
+		if config.Name == "" || config.Issuer == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("%w: name, issuer and client_id are required", common.ErrOidcConfig)
+		}
+		if slices.Contains(names, config.Name) {
+			return nil, fmt.Errorf("%w: duplicate provider %s", common.ErrOidcConfig, config.Name)
+		}
+		names = append(names, config.Name)
+
+		if config.DisplayName == "" {
+			config.DisplayName = config.Name
+		}
+		if len(config.Scopes) == 0 {
+			config.Scopes = cli.StringSlice("oidc-scopes")
+		}
+		if !slices.Contains(config.Scopes, oidc.ScopeOpenID) {
+			config.Scopes = append([]string{oidc.ScopeOpenID}, config.Scopes...)
+		}
+		if config.RedirectUrl == "" {
+			config.RedirectUrl = cli.String("oidc-redirect-url")
+		}
+	}
+	return &OidcProvider{
+		ctx:     ctx,
+		client:  &http.Client{Timeout: cli.Duration("oidc-timeout")},
+		configs: configs,
+		issuers: map[string]*oidcIssuer{},
+	}, nil
+}
+
+func (rcv *OidcProvider) Providers() []*OidcProviderConfig {
+	return rcv.configs
+}
+
+func (rcv *OidcProvider) Provider(name string) (*OidcProviderConfig, error) {
+	for _, config := range rcv.configs {
+		if config.Name == name {
+			return config, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", common.ErrOidcProvider, name)
+}
+
+// AuthCodeUrl returns the provider authorization url, the nonce is bound to the
+// ID token and the verifier to the code exchange (PKCE)
+func (rcv *OidcProvider) AuthCodeUrl(name, state, nonce, verifier string) (string, error) {
+	issuer, err := rcv.issuer(name)
+	if err != nil {
+		return "", err
+	}
+	return issuer.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems the code and verifies the ID token signature against the
+// provider keys, the audience, the expiry and the nonce
+func (rcv *OidcProvider) Exchange(name, code, verifier, nonce string) (*OidcIdentity, error) {
+	issuer, err := rcv.issuer(name)
+	if err != nil {
+		return nil, err
+	}
+	ctx := oidc.ClientContext(context.Background(), rcv.client)
+
+	token, err := issuer.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, "missing id token")
+	}
+	idToken, err := issuer.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, "nonce mismatch")
+	}
+	claims := &struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+	}{}
+	if err := idToken.Claims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrOidcIdentity, err)
+	}
+	// some providers send the flag as a string
+	verified := claims.EmailVerified == true || claims.EmailVerified == "true"
+
+	return &OidcIdentity{
+		Provider:      name,
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: verified,
+		Firstname:     claims.GivenName,
+		Lastname:      claims.FamilyName,
+	}, nil
+}
+
+func (rcv *OidcProvider) issuer(name string) (*oidcIssuer, error) {
+	config, err := rcv.Provider(name)
+	if err != nil {
+		return nil, err
+	}
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	if issuer, ok := rcv.issuers[name]; ok {
+		return issuer, nil
+	}
+	// the key set of the provider keeps using the client after the discovery
+	discovered, err := oidc.NewProvider(oidc.ClientContext(context.Background(), rcv.client), config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrOidcDiscover, err)
+	}
+	issuer := &oidcIssuer{
+		oauth2: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			Endpoint:     discovered.Endpoint(),
+			RedirectURL:  config.RedirectUrl,
+			Scopes:       config.Scopes,
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}
+	rcv.issuers[name] = issuer
+	return issuer, nil
+}
@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"brickwall/internal/common"
+)
+
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+var (
+	errPasswordMismatch   = errors.New("password does not match the hash")
+	errPasswordHashFormat = errors.New("unknown password hash format")
+)
+
+type IPasswordHasherProvider interface {
+	Hash(string) (string, error)
+	Compare(string, string) error
+	NeedsRehash(string) bool
+}
+
+// passwordHasher is one hashing scheme, the stored hash tells the scheme it was made by
+type passwordHasher interface {
+	owns(string) bool
+	hash(string) (string, error)
+	compare(string, string) error
+	outdated(string) bool
+}
+
+// PasswordHasherProvider hashes the new passwords with the configured scheme
+// and still verifies the hashes made by the other ones, so the stored hashes
+// are upgraded one sign-in at a time
+type PasswordHasherProvider struct {
+	current passwordHasher
+	hashers []passwordHasher
+}
+
+func NewPasswordHasherProvider(ctx context.Context) (IPasswordHasherProvider, error) {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	argon2id := &argon2idHasher{
+		memory:      uint32(cli.Int("password-argon2-memory")),
+		iterations:  uint32(cli.Int("password-argon2-iterations")),
+		parallelism: uint8(cli.Int("password-argon2-parallelism")),
+	}
+	if argon2id.iterations < 1 || argon2id.parallelism < 1 || argon2id.memory < 8*uint32(argon2id.parallelism) {
+		return nil, fmt.Errorf("%w: %v", common.ErrPasswordHasherConfig, "argon2 needs 1 iteration, 1 lane and 8 KiB per lane at least")
+	}
+	bcryptCost := int(cli.Int("password-bcrypt-cost"))
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("%w: bcrypt cost out of range %d-%d", common.ErrPasswordHasherConfig, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	provider := &PasswordHasherProvider{
+		hashers: []passwordHasher{argon2id, &bcryptHasher{cost: bcryptCost}},
+	}
+	switch algorithm := cli.String("password-hash-algorithm"); algorithm {
+	case PasswordHashArgon2id:
+		provider.current = provider.hashers[0]
+	case PasswordHashBcrypt:
+		provider.current = provider.hashers[1]
+	default:
+		return nil, fmt.Errorf("%w: unknown algorithm %s", common.ErrPasswordHasherConfig, algorithm)
+	}
+	return provider, nil
+}
+
+func (rcv *PasswordHasherProvider) Hash(password string) (string, error) {
+	return rcv.current.hash(password)
+}
+
+// Compare checks the password against the stored hash of any known scheme
+func (rcv *PasswordHasherProvider) Compare(encoded, password string) error {
+	hasher := rcv.hasher(encoded)
+	if hasher == nil {
+		return errPasswordHashFormat
+	}
+	return hasher.compare(encoded, password)
+}
+
+// NeedsRehash reports whether the hash was made by another scheme, or with
+// weaker parameters than the configured ones
+func (rcv *PasswordHasherProvider) NeedsRehash(encoded string) bool {
+	hasher := rcv.hasher(encoded)
+	if hasher == nil {
+		return false
+	}
+	return hasher != rcv.current || hasher.outdated(encoded)
+}
+
+func (rcv *PasswordHasherProvider) hasher(encoded string) passwordHasher {
+	for _, hasher := range rcv.hashers {
+		if hasher.owns(encoded) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+// argon2idHasher stores the hashes in the PHC string format
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (rcv *argon2idHasher) owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (rcv *argon2idHasher) hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, rcv.iterations, rcv.memory, rcv.parallelism, argon2idKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, rcv.memory, rcv.iterations, rcv.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (rcv *argon2idHasher) compare(encoded, password string) error {
+	hash, err := rcv.decode(encoded)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), hash.salt, hash.iterations, hash.memory, hash.parallelism, uint32(len(hash.key)))
+	if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+func (rcv *argon2idHasher) outdated(encoded string) bool {
+	hash, err := rcv.decode(encoded)
+	if err != nil {
+		return true
+	}
+	return hash.memory != rcv.memory || hash.iterations != rcv.iterations || hash.parallelism != rcv.parallelism ||
+		len(hash.salt) < argon2idSaltLength || len(hash.key) != argon2idKeyLength
+}
+
+func (rcv *argon2idHasher) decode(encoded string) (*argon2idHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, errPasswordHashFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errPasswordHashFormat
+	}
+	hash := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism); err != nil {
+		return nil, errPasswordHashFormat
+	}
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errPasswordHashFormat
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return nil, errPasswordHashFormat
+	}
+	return hash, nil
+}
+
+// bcryptHasher verifies the hashes made before argon2id, including the ones
+// seeded by the migrations through pgcrypto
+type bcryptHasher struct {
+	cost int
+}
+
+func (rcv *bcryptHasher) owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (rcv *bcryptHasher) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), rcv.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (rcv *bcryptHasher) compare(encoded, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+}
+
+func (rcv *bcryptHasher) outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < rcv.cost
+}
@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/urfave/cli/v3"
+
+	"brickwall/internal/common"
+)
+
+const (
+	PasswordClassLower  = "lower"
+	PasswordClassUpper  = "upper"
+	PasswordClassDigit  = "digit"
+	PasswordClassSymbol = "symbol"
+
+	passwordField = "password"
+
+	// identities shorter than that are not looked for in the password
+	minIdentityLength = 3
+)
+
+type IPasswordPolicyProvider interface {
+	Validate(string, ...string) error
+}
+
+// PasswordPolicyProvider checks the new passwords, the breached list is kept
+// as SHA-1 digests so plain and HIBP formatted lists are accepted alike
+type PasswordPolicyProvider struct {
+	minLength int
+	classes   []string
+	breached  map[[sha1.Size]byte]struct{}
+}
+
+func NewPasswordPolicyProvider(ctx context.Context) (IPasswordPolicyProvider, error) {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	policy := &PasswordPolicyProvider{
+		minLength: int(cli.Int("password-min-length")),
+		breached:  map[[sha1.Size]byte]struct{}{},
+	}
+	for _, class := range cli.StringSlice("password-required-classes") {
+		switch class {
+		case PasswordClassLower, PasswordClassUpper, PasswordClassDigit, PasswordClassSymbol:
+			policy.classes = append(policy.classes, class)
+		default:
+			return nil, fmt.Errorf("%w: unknown character class %s", common.ErrPasswordPolicyConfig, class)
+		}
+	}
+	if file := cli.String("password-breached-file"); file != "" {
+		if err := policy.loadBreached(file); err != nil {
+			return nil, fmt.Errorf("%w: %v", common.ErrPasswordPolicyConfig, err)
+		}
+	}
+	return policy, nil
+}
+
+// Validate checks the password against the policy, the identities are the
+// username and the emails the password must not contain. Every broken rule is
+// reported as a detail of the returned error.
+func (rcv *PasswordPolicyProvider) Validate(password string, identities ...string) error {
+	details := []*common.ExceptionDetail{}
+	violation := func(code, message string) {
+		details = append(details, &common.ExceptionDetail{Field: passwordField, Code: code, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < rcv.minLength {
+		violation("too_short", fmt.Sprintf("must be at least %d characters long", rcv.minLength))
+	}
+	for _, class := range rcv.classes {
+		if !strings.ContainsFunc(password, passwordClasses[class]) {
+			violation("missing_"+class, "must contain "+passwordClassNames[class])
+		}
+	}
+	if containsIdentity(password, identities) {
+		violation("contains_identity", "must not contain the username or the email")
+	}
+	if _, ok := rcv.breached[sha1.Sum([]byte(password))]; ok {
+		violation("breached", "appears in a list of breached passwords")
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(details))
+	for _, detail := range details {
+		messages = append(messages, detail.Message)
+	}
+	return &common.ValidationError{
+		Err:     fmt.Errorf("%w: %v", common.ErrPasswordPolicy, strings.Join(messages, ", ")),
+		Details: details,
+	}
+}
+
+// loadBreached reads one password per line, or the SHA-1 digest of one as in
+// the HIBP downloads, the occurrence count after the colon is ignored
+func (rcv *PasswordPolicyProvider) loadBreached(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		digest, ok := breachedDigest(line)
+		if !ok {
+			digest = sha1.Sum([]byte(line))
+		}
+		rcv.breached[digest] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+var passwordClasses = map[string]func(rune) bool{
+	PasswordClassLower:  unicode.IsLower,
+	PasswordClassUpper:  unicode.IsUpper,
+	PasswordClassDigit:  unicode.IsDigit,
+	PasswordClassSymbol: func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) },
+}
+
+var passwordClassNames = map[string]string{
+	PasswordClassLower:  "a lowercase letter",
+	PasswordClassUpper:  "an uppercase letter",
+	PasswordClassDigit:  "a digit",
+	PasswordClassSymbol: "a symbol",
+}
+
+func breachedDigest(line string) ([sha1.Size]byte, bool) {
+	var digest [sha1.Size]byte
+
+	hash, _, _ := strings.Cut(line, ":")
+	if len(hash) != hex.EncodedLen(sha1.Size) {
+		return digest, false
+	}
+	if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+		return digest, false
+	}
+	return digest, true
+}
+
+// containsIdentity looks for the identities, and the local part of the emails,
+// in the password regardless of the case
+func containsIdentity(password string, identities []string) bool {
+	password = strings.ToLower(password)
+	for _, identity := range identities {
+		identity = strings.ToLower(strings.TrimSpace(identity))
+		candidates := []string{identity}
+		if local, _, ok := strings.Cut(identity, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= minIdentityLength && strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
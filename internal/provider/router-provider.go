@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +19,7 @@ func (rcv *GinLoggerAdapter) Write(p []byte) (n int, err error) {
 }
 
 type IRouterProvider interface {
-	Init() IRouterProvider
+	Init() (IRouterProvider, error)
 	Engine() *gin.Engine
 }
 
@@ -31,14 +32,21 @@ func NewRouterProvider(ctx context.Context) IRouterProvider {
 	return &RouterProvider{ctx: ctx}
 }
 
-func (rcv *RouterProvider) Init() IRouterProvider {
+// Init builds the engine, the client address is taken from X-Forwarded-For
+// only when the request comes from a trusted proxy
+func (rcv *RouterProvider) Init() (IRouterProvider, error) {
+	cli := rcv.ctx.Value(common.KeyCommand).(*cli.Command)
+
 	gin.DefaultWriter = &GinLoggerAdapter{}
 	gin.DefaultErrorWriter = &GinLoggerAdapter{}
 
 	rcv.engine = gin.Default()
+	if err := rcv.engine.SetTrustedProxies(cli.StringSlice("server-trusted-proxies")); err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrTrustedProxies, err)
+	}
 	rcv.engine.Use(cors(rcv.ctx))
 
-	return rcv
+	return rcv, nil
 }
 
 func (rcv *RouterProvider) Engine() *gin.Engine {
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/urfave/cli/v3"
+
+	"brickwall/internal/common"
+)
+
+// WebAuthnUser is the user of a ceremony, the user handle is the user id so the
+// discoverable sign-in resolves the user without the username
+type WebAuthnUser struct {
+	ID          string
+	Name        string
+	Credentials []webauthn.Credential
+}
+
+func (rcv *WebAuthnUser) WebAuthnID() []byte {
+	return []byte(rcv.ID)
+}
+
+func (rcv *WebAuthnUser) WebAuthnName() string {
+	return rcv.Name
+}
+
+func (rcv *WebAuthnUser) WebAuthnDisplayName() string {
+	return rcv.Name
+}
+
+func (rcv *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return rcv.Credentials
+}
+
+// WebAuthnUserHandler loads the user and the credentials by the user handle
+type WebAuthnUserHandler func(userID string) (*WebAuthnUser, error)
+
+type IWebAuthnProvider interface {
+	BeginRegistration(*WebAuthnUser) (*protocol.CredentialCreation, *webauthn.SessionData, error)
+	FinishRegistration(*WebAuthnUser, *webauthn.SessionData, []byte) (*webauthn.Credential, error)
+	BeginLogin(*WebAuthnUser) (*protocol.CredentialAssertion, *webauthn.SessionData, error)
+	FinishLogin(*WebAuthnUser, *webauthn.SessionData, []byte) (*webauthn.Credential, error)
+	BeginPasskeyLogin() (*protocol.CredentialAssertion, *webauthn.SessionData, error)
+	FinishPasskeyLogin(WebAuthnUserHandler, *webauthn.SessionData, []byte) (*WebAuthnUser, *webauthn.Credential, error)
+}
+
+type WebAuthnProvider struct {
+	ctx              context.Context
+	webauthn         *webauthn.WebAuthn
+	userVerification protocol.UserVerificationRequirement
+}
+
+func NewWebAuthnProvider(ctx context.Context) (IWebAuthnProvider, error) {
+	cli := ctx.Value(common.KeyCommand).(*cli.Command)
+
+	userVerification := protocol.UserVerificationRequirement(cli.String("webauthn-user-verification"))
+	switch userVerification {
+	case protocol.VerificationRequired, protocol.VerificationPreferred, protocol.VerificationDiscouraged:
+	default:
+		return nil, fmt.Errorf("%w: unknown user verification %s", common.ErrWebAuthnConfig, userVerification)
+	}
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    cli.Duration("webauthn-state-expiration"),
+		TimeoutUVD: cli.Duration("webauthn-state-expiration"),
+	}
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cli.String("webauthn-rp-id"),
+		RPDisplayName: cli.String("webauthn-rp-name"),
+		RPOrigins:     cli.StringSlice("webauthn-rp-origins"),
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: userVerification,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnConfig, err)
+	}
+	return &WebAuthnProvider{
+		ctx:              ctx,
+		webauthn:         wa,
+		userVerification: userVerification,
+	}, nil
+}
+
+// BeginRegistration returns the creation options, the registered credentials
+// are excluded so one authenticator is not registered twice
+func (rcv *WebAuthnProvider) BeginRegistration(user *WebAuthnUser) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
+	return rcv.webauthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.Credentials).CredentialDescriptors()),
+	)
+}
+
+func (rcv *WebAuthnProvider) FinishRegistration(user *WebAuthnUser, session *webauthn.SessionData, response []byte) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	credential, err := rcv.webauthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	return credential, nil
+}
+
+// BeginLogin returns the assertion options of the known user, used by the second factor
+func (rcv *WebAuthnProvider) BeginLogin(user *WebAuthnUser) (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	return rcv.webauthn.BeginLogin(user, webauthn.WithUserVerification(rcv.userVerification))
+}
+
+func (rcv *WebAuthnProvider) FinishLogin(user *WebAuthnUser, session *webauthn.SessionData, response []byte) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	credential, err := rcv.webauthn.ValidateLogin(user, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	return rcv.checkClone(credential)
+}
+
+// BeginPasskeyLogin returns the assertion options of the passwordless sign-in,
+// the passkey replaces the password and the second factor so user verification
+// is always required
+func (rcv *WebAuthnProvider) BeginPasskeyLogin() (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	return rcv.webauthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+}
+
+func (rcv *WebAuthnProvider) FinishPasskeyLogin(handler WebAuthnUserHandler, session *webauthn.SessionData, response []byte) (*WebAuthnUser, *webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	var user *WebAuthnUser
+	_, credential, err := rcv.webauthn.ValidatePasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		found, err := handler(string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+		user = found
+		return found, nil
+	}, *session, parsed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, err)
+	}
+	credential, err = rcv.checkClone(credential)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, credential, nil
+}
+
+// checkClone refuses the credential whose signature counter went backwards
+func (rcv *WebAuthnProvider) checkClone(credential *webauthn.Credential) (*webauthn.Credential, error) {
+	if credential.Authenticator.CloneWarning {
+		return nil, fmt.Errorf("%w: %v", common.ErrWebAuthnCredential, "signature counter mismatch, the authenticator may be cloned")
+	}
+	return credential, nil
+}
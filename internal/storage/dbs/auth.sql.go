@@ -47,6 +47,71 @@ func (q *Queries) AuthSelectUserCredentials(ctx context.Context, username string
 	return &i, err
 }
 
+const authSelectMe = `-- name: AuthSelectMe :one
+select v.user_id, v.profile_id, v.user_username, v.user_is_blocked, v.user_is_checked, v.profile_firstname, v.profile_lastname, v.profile_gender, v.profile_birthday, v.profile_avatar_url, v.profile_enable_2fa, v.profile_secret_2fa
+  from v_user_profile v
+ where v.user_id = $1
+`
+
+// AuthSelectMe
+//
+//	select v.user_id, v.profile_id, v.user_username, v.user_is_blocked, v.user_is_checked, v.profile_firstname, v.profile_lastname, v.profile_gender, v.profile_birthday, v.profile_avatar_url, v.profile_enable_2fa, v.profile_secret_2fa
+//	  from v_user_profile v
+//	 where v.user_id = $1
+func (q *Queries) AuthSelectMe(ctx context.Context, userID string) (*VUserProfile, error) {
+	row := q.db.QueryRow(ctx, authSelectMe, userID)
+	var i VUserProfile
+	err := row.Scan(
+		&i.UserID,
+		&i.ProfileID,
+		&i.UserUsername,
+		&i.UserIsBlocked,
+		&i.UserIsChecked,
+		&i.ProfileFirstname,
+		&i.ProfileLastname,
+		&i.ProfileGender,
+		&i.ProfileBirthday,
+		&i.ProfileAvatarUrl,
+		&i.ProfileEnable2fa,
+		&i.ProfileSecret2fa,
+	)
+	return &i, err
+}
+
+const authSelectUserByEmail = `-- name: AuthSelectUserByEmail :one
+select u.id, u.username, u.is_blocked, u.is_checked
+  from users u
+  join contact c on c.user_id = u.id
+ where c.class = 'email'
+   and c.content = $1
+`
+
+type AuthSelectUserByEmailRow struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	IsBlocked bool   `json:"is_blocked"`
+	IsChecked bool   `json:"is_checked"`
+}
+
+// AuthSelectUserByEmail
+//
+//	select u.id, u.username, u.is_blocked, u.is_checked
+//	  from users u
+//	  join contact c on c.user_id = u.id
+//	 where c.class = 'email'
+//	   and c.content = $1
+func (q *Queries) AuthSelectUserByEmail(ctx context.Context, email string) (*AuthSelectUserByEmailRow, error) {
+	row := q.db.QueryRow(ctx, authSelectUserByEmail, email)
+	var i AuthSelectUserByEmailRow
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.IsBlocked,
+		&i.IsChecked,
+	)
+	return &i, err
+}
+
 const authUpdateVisitedAt = `-- name: AuthUpdateVisitedAt :one
 update users set visited_at = now() where id = $1
        returning id, username, checked_at, visited_at, created_at
@@ -76,3 +141,24 @@ func (q *Queries) AuthUpdateVisitedAt(ctx context.Context, id string) (*AuthUpda
 	)
 	return &i, err
 }
+
+const authUpdatePasswordByID = `-- name: AuthUpdatePasswordByID :one
+update users set password = $1 where id = $2
+       returning id
+`
+
+type AuthUpdatePasswordByIDParams struct {
+	Password string `json:"password"`
+	ID       string `json:"id"`
+}
+
+// AuthUpdatePasswordByID
+//
+//	update users set password = $1 where id = $2
+//	       returning id
+func (q *Queries) AuthUpdatePasswordByID(ctx context.Context, arg *AuthUpdatePasswordByIDParams) (string, error) {
+	row := q.db.QueryRow(ctx, authUpdatePasswordByID, arg.Password, arg.ID)
+	var id string
+	err := row.Scan(&id)
+	return id, err
+}
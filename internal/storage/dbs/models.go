@@ -8,6 +8,19 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiKey struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	KeyHash    string           `json:"key_hash"`
+	Scopes     []string         `json:"scopes"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
+}
+
 type Contact struct {
 	ID        string           `json:"id"`
 	UserID    string           `json:"user_id"`
@@ -45,16 +58,76 @@ type Currency struct {
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
 }
 
+type MagicLink struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type OauthClient struct {
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	SecretHash   string           `json:"secret_hash"`
+	IsPublic     bool             `json:"is_public"`
+	RedirectUris []string         `json:"redirect_uris"`
+	GrantTypes   []string         `json:"grant_types"`
+	Scopes       []string         `json:"scopes"`
+	UserID       pgtype.Text      `json:"user_id"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
+type OauthConsent struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	ClientID  string           `json:"client_id"`
+	Scopes    []string         `json:"scopes"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type PasswordReset struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	TokenHash string           `json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type Permission struct {
+	ID          string           `json:"id"`
+	Code        string           `json:"code"`
+	Description string           `json:"description"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+}
+
 type Profile struct {
+	ID              string           `json:"id"`
+	UserID          string           `json:"user_id"`
+	Firstname       string           `json:"firstname"`
+	Lastname        string           `json:"lastname"`
+	Gender          string           `json:"gender"`
+	Birthday        pgtype.Date      `json:"birthday"`
+	AvatarUrl       string           `json:"avatar_url"`
+	Enable2fa       bool             `json:"enable_2fa"`
+	Secret2fa       pgtype.Text      `json:"secret_2fa"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+	EnableMagicLink bool             `json:"enable_magic_link"`
+}
+
+type RecoveryCode struct {
 	ID        string           `json:"id"`
 	UserID    string           `json:"user_id"`
-	Firstname string           `json:"firstname"`
-	Lastname  string           `json:"lastname"`
-	Gender    string           `json:"gender"`
-	Birthday  pgtype.Date      `json:"birthday"`
-	AvatarUrl string           `json:"avatar_url"`
-	Enable2fa bool             `json:"enable_2fa"`
-	Secret2fa pgtype.Text      `json:"secret_2fa"`
+	CodeHash  string           `json:"code_hash"`
+	UsedAt    pgtype.Timestamp `json:"used_at"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
 }
@@ -66,6 +139,14 @@ type Role struct {
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
 }
 
+type RolePermission struct {
+	ID           string           `json:"id"`
+	RoleID       string           `json:"role_id"`
+	PermissionID string           `json:"permission_id"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
 type User struct {
 	ID        string           `json:"id"`
 	Username  string           `json:"username"`
@@ -77,6 +158,38 @@ type User struct {
 	VisitedAt pgtype.Timestamp `json:"visited_at"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	IsService bool             `json:"is_service"`
+}
+
+type UserIdentity struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	Provider   string           `json:"provider"`
+	Subject    string           `json:"subject"`
+	Email      string           `json:"email"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
+}
+
+type UserRole struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	RoleID    string           `json:"role_id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type UserSession struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	FamilyID   string           `json:"family_id"`
+	UserAgent  string           `json:"user_agent"`
+	IpAddress  string           `json:"ip_address"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
 }
 
 type VUserProfile struct {
@@ -93,3 +206,14 @@ type VUserProfile struct {
 	ProfileEnable2fa pgtype.Bool `json:"profile_enable_2fa"`
 	ProfileSecret2fa pgtype.Text `json:"profile_secret_2fa"`
 }
+
+type WebauthnCredential struct {
+	ID           string           `json:"id"`
+	UserID       string           `json:"user_id"`
+	Name         string           `json:"name"`
+	CredentialID []byte           `json:"credential_id"`
+	Credential   []byte           `json:"credential"`
+	LastUsedAt   pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
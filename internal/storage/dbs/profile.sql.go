@@ -27,14 +27,14 @@ func (q *Queries) ProfileCount(ctx context.Context) (int64, error) {
 
 const profileIsExists = `-- name: ProfileIsExists :one
 select case when exists (
-    select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at from profile p where p.user_id = $1
+    select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link from profile p where p.user_id = $1
 ) then cast(1 as bit) else cast(0 as bit) end
 `
 
 // ProfileIsExists
 //
 //	select case when exists (
-//	    select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at from profile p where p.user_id = $1
+//	    select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link from profile p where p.user_id = $1
 //	) then cast(1 as bit) else cast(0 as bit) end
 func (q *Queries) ProfileIsExists(ctx context.Context, userID string) (pgtype.Bits, error) {
 	row := q.db.QueryRow(ctx, profileIsExists, userID)
@@ -48,7 +48,7 @@ insert into profile(
     user_id, firstname, lastname
 ) values(
     $1, $2, $3
-) returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+) returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 `
 
 type ProfileNewParams struct {
@@ -63,7 +63,7 @@ type ProfileNewParams struct {
 //	    user_id, firstname, lastname
 //	) values(
 //	    $1, $2, $3
-//	) returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+//	) returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 func (q *Queries) ProfileNew(ctx context.Context, arg *ProfileNewParams) (*Profile, error) {
 	row := q.db.QueryRow(ctx, profileNew, arg.UserID, arg.Firstname, arg.Lastname)
 	var i Profile
@@ -79,12 +79,13 @@ func (q *Queries) ProfileNew(ctx context.Context, arg *ProfileNewParams) (*Profi
 		&i.Secret2fa,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnableMagicLink,
 	)
 	return &i, err
 }
 
 const profileSelect = `-- name: ProfileSelect :many
-select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
   from profile p
  order by $1::text
  limit $3 offset $2
@@ -98,7 +99,7 @@ type ProfileSelectParams struct {
 
 // ProfileSelect
 //
-//	select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+//	select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 //	  from profile p
 //	 order by $1::text
 //	 limit $3 offset $2
@@ -123,6 +124,7 @@ func (q *Queries) ProfileSelect(ctx context.Context, arg *ProfileSelectParams) (
 			&i.Secret2fa,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EnableMagicLink,
 		); err != nil {
 			return nil, err
 		}
@@ -135,12 +137,12 @@ func (q *Queries) ProfileSelect(ctx context.Context, arg *ProfileSelectParams) (
 }
 
 const profileSelectByID = `-- name: ProfileSelectByID :one
-select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at from profile p where p.id = $1
+select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link from profile p where p.id = $1
 `
 
 // ProfileSelectByID
 //
-//	select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at from profile p where p.id = $1
+//	select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link from profile p where p.id = $1
 func (q *Queries) ProfileSelectByID(ctx context.Context, id string) (*Profile, error) {
 	row := q.db.QueryRow(ctx, profileSelectByID, id)
 	var i Profile
@@ -156,17 +158,18 @@ func (q *Queries) ProfileSelectByID(ctx context.Context, id string) (*Profile, e
 		&i.Secret2fa,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnableMagicLink,
 	)
 	return &i, err
 }
 
 const profileSelectByUserID = `-- name: ProfileSelectByUserID :one
-select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at from profile p where p.user_id = $1
+select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link from profile p where p.user_id = $1
 `
 
 // ProfileSelectByUserID
 //
-//	select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at from profile p where p.user_id = $1
+//	select id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link from profile p where p.user_id = $1
 func (q *Queries) ProfileSelectByUserID(ctx context.Context, id string) (*Profile, error) {
 	row := q.db.QueryRow(ctx, profileSelectByUserID, id)
 	var i Profile
@@ -182,6 +185,7 @@ func (q *Queries) ProfileSelectByUserID(ctx context.Context, id string) (*Profil
 		&i.Secret2fa,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnableMagicLink,
 	)
 	return &i, err
 }
@@ -189,7 +193,7 @@ func (q *Queries) ProfileSelectByUserID(ctx context.Context, id string) (*Profil
 const profileUpdate2FAById = `-- name: ProfileUpdate2FAById :one
 update profile
    set enable_2fa = $1, secret_2fa = $2
- where id = $3 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+ where id = $3 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 `
 
 type ProfileUpdate2FAByIdParams struct {
@@ -202,7 +206,7 @@ type ProfileUpdate2FAByIdParams struct {
 //
 //	update profile
 //	   set enable_2fa = $1, secret_2fa = $2
-//	 where id = $3 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+//	 where id = $3 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 func (q *Queries) ProfileUpdate2FAById(ctx context.Context, arg *ProfileUpdate2FAByIdParams) (*Profile, error) {
 	row := q.db.QueryRow(ctx, profileUpdate2FAById, arg.Enable2fa, arg.Secret2fa, arg.ID)
 	var i Profile
@@ -218,6 +222,7 @@ func (q *Queries) ProfileUpdate2FAById(ctx context.Context, arg *ProfileUpdate2F
 		&i.Secret2fa,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnableMagicLink,
 	)
 	return &i, err
 }
@@ -225,7 +230,7 @@ func (q *Queries) ProfileUpdate2FAById(ctx context.Context, arg *ProfileUpdate2F
 const profileUpdateAvatarUrlById = `-- name: ProfileUpdateAvatarUrlById :one
 update profile
    set avatar_url = $1
- where id = $2 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+ where id = $2 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 `
 
 type ProfileUpdateAvatarUrlByIdParams struct {
@@ -237,7 +242,7 @@ type ProfileUpdateAvatarUrlByIdParams struct {
 //
 //	update profile
 //	   set avatar_url = $1
-//	 where id = $2 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+//	 where id = $2 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 func (q *Queries) ProfileUpdateAvatarUrlById(ctx context.Context, arg *ProfileUpdateAvatarUrlByIdParams) (*Profile, error) {
 	row := q.db.QueryRow(ctx, profileUpdateAvatarUrlById, arg.AvatarUrl, arg.ID)
 	var i Profile
@@ -253,6 +258,7 @@ func (q *Queries) ProfileUpdateAvatarUrlById(ctx context.Context, arg *ProfileUp
 		&i.Secret2fa,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnableMagicLink,
 	)
 	return &i, err
 }
@@ -260,7 +266,7 @@ func (q *Queries) ProfileUpdateAvatarUrlById(ctx context.Context, arg *ProfileUp
 const profileUpdateCommonById = `-- name: ProfileUpdateCommonById :one
 update profile
    set firstname = $1, lastname = $2, gender = $3, birthday = $4
- where id = $5 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+ where id = $5 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 `
 
 type ProfileUpdateCommonByIdParams struct {
@@ -275,7 +281,7 @@ type ProfileUpdateCommonByIdParams struct {
 //
 //	update profile
 //	   set firstname = $1, lastname = $2, gender = $3, birthday = $4
-//	 where id = $5 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at
+//	 where id = $5 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
 func (q *Queries) ProfileUpdateCommonById(ctx context.Context, arg *ProfileUpdateCommonByIdParams) (*Profile, error) {
 	row := q.db.QueryRow(ctx, profileUpdateCommonById,
 		arg.Firstname,
@@ -297,6 +303,43 @@ func (q *Queries) ProfileUpdateCommonById(ctx context.Context, arg *ProfileUpdat
 		&i.Secret2fa,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EnableMagicLink,
+	)
+	return &i, err
+}
+
+const profileUpdateMagicLinkByUserID = `-- name: ProfileUpdateMagicLinkByUserID :one
+update profile
+   set enable_magic_link = $1
+ where user_id = $2 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
+`
+
+type ProfileUpdateMagicLinkByUserIDParams struct {
+	EnableMagicLink bool   `json:"enable_magic_link"`
+	UserID          string `json:"user_id"`
+}
+
+// ProfileUpdateMagicLinkByUserID
+//
+//	update profile
+//	   set enable_magic_link = $1
+//	 where user_id = $2 returning id, user_id, firstname, lastname, gender, birthday, avatar_url, enable_2fa, secret_2fa, created_at, updated_at, enable_magic_link
+func (q *Queries) ProfileUpdateMagicLinkByUserID(ctx context.Context, arg *ProfileUpdateMagicLinkByUserIDParams) (*Profile, error) {
+	row := q.db.QueryRow(ctx, profileUpdateMagicLinkByUserID, arg.EnableMagicLink, arg.UserID)
+	var i Profile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Gender,
+		&i.Birthday,
+		&i.AvatarUrl,
+		&i.Enable2fa,
+		&i.Secret2fa,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EnableMagicLink,
 	)
 	return &i, err
 }
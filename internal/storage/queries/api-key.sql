@@ -0,0 +1,29 @@
+-- name: ApiKeyNew :one
+insert into api_key(
+    user_id, name, prefix, key_hash, scopes, expires_at
+) values(
+    @user_id, @name, @prefix, @key_hash, @scopes, coalesce(sqlc.narg(expires_at)::timestamp, '1000-01-01'::timestamp)
+) returning id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at;
+
+-- name: ApiKeySelectByUserID :many
+select id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+  from api_key k
+ where k.user_id = @user_id
+ order by k.created_at;
+
+-- name: ApiKeySelectActiveByPrefix :one
+select *
+  from api_key k
+ where k.prefix = @prefix
+   and (k.expires_at = '1000-01-01'::timestamp or k.expires_at > timezone('utc', now()));
+
+-- name: ApiKeyUpdateLastUsedAtByID :exec
+update api_key
+   set last_used_at = timezone('utc', now())
+ where id = @id
+   and last_used_at < timezone('utc', now()) - interval '1 minute';
+
+-- name: ApiKeyDeleteByUserIDID :one
+delete from api_key
+ where user_id = @user_id and id = @id
+ returning id;
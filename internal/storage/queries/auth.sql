@@ -3,6 +3,23 @@ select id, username, password, is_blocked, is_checked, blocked_at, checked_at
   from users u
  where u.username = @username;
 
+-- name: AuthSelectUserByEmail :one
+select u.id, u.username, u.is_blocked, u.is_checked
+  from users u
+  join contact c on c.user_id = u.id
+ where c.class = 'email'
+   and c.content = @email;
+
+-- name: AuthSelectMe :one
+select v.*
+  from v_user_profile v
+ where v.user_id = @user_id;
+
 -- name: AuthUpdateVisitedAt :one
 update users set visited_at = now() where id = @id
        returning id, username, checked_at, visited_at, created_at;
+
+
+-- name: AuthUpdatePasswordByID :one
+update users set password = @password where id = @id
+       returning id;
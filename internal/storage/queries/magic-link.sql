@@ -0,0 +1,17 @@
+-- name: MagicLinkNew :one
+insert into magic_link(
+    user_id, token_hash, expires_at
+) values(
+    @user_id, @token_hash, @expires_at
+) returning *;
+
+-- name: MagicLinkUseByTokenHash :one
+update magic_link
+   set used_at = timezone('utc', now())
+ where token_hash = @token_hash
+   and used_at = '1000-01-01'::timestamp
+   and expires_at > timezone('utc', now())
+ returning *;
+
+-- name: MagicLinkDeleteByUserID :exec
+delete from magic_link where user_id = @user_id;
@@ -0,0 +1,17 @@
+-- name: OauthClientNew :one
+insert into oauth_client(
+    name, secret_hash, is_public, redirect_uris, grant_types, scopes, user_id
+) values(
+    @name, @secret_hash, @is_public, @redirect_uris, @grant_types, @scopes, sqlc.narg(user_id)
+) returning id, name, is_public, redirect_uris, grant_types, scopes, user_id, created_at;
+
+-- name: OauthClientSelect :many
+select id, name, is_public, redirect_uris, grant_types, scopes, user_id, created_at
+  from oauth_client c
+ order by c.name;
+
+-- name: OauthClientSelectByID :one
+select * from oauth_client c where c.id = @id;
+
+-- name: OauthClientDeleteByID :one
+delete from oauth_client where id = @id returning id;
@@ -0,0 +1,24 @@
+-- name: OauthConsentUpsert :exec
+insert into oauth_consent(
+    user_id, client_id, scopes
+) values(
+    @user_id, @client_id, @scopes
+) on conflict (user_id, client_id) do update
+   set scopes = excluded.scopes;
+
+-- name: OauthConsentSelectByUserIDClientID :one
+select *
+  from oauth_consent oc
+ where oc.user_id = @user_id and oc.client_id = @client_id;
+
+-- name: OauthConsentSelectByUserID :many
+select oc.id, oc.client_id, c.name as client_name, oc.scopes, oc.created_at, oc.updated_at
+  from oauth_consent oc
+  join oauth_client c on c.id = oc.client_id
+ where oc.user_id = @user_id
+ order by c.name;
+
+-- name: OauthConsentDeleteByUserIDClientID :one
+delete from oauth_consent
+ where user_id = @user_id and client_id = @client_id
+ returning id;
@@ -0,0 +1,17 @@
+-- name: PasswordResetNew :one
+insert into password_reset(
+    user_id, token_hash, expires_at
+) values(
+    @user_id, @token_hash, @expires_at
+) returning *;
+
+-- name: PasswordResetUseByTokenHash :one
+update password_reset
+   set used_at = timezone('utc', now())
+ where token_hash = @token_hash
+   and used_at = '1000-01-01'::timestamp
+   and expires_at > timezone('utc', now())
+ returning *;
+
+-- name: PasswordResetDeleteByUserID :exec
+delete from password_reset where user_id = @user_id;
@@ -0,0 +1,24 @@
+-- name: PermissionNew :one
+insert into permission(code, description) values(@code, @description) returning *;
+
+-- name: PermissionCount :one
+select count(*) from permission;
+
+-- name: PermissionSelect :many
+select *
+  from permission p
+ order by @sql_order::text
+ limit @sql_limit offset @sql_offset;
+
+-- name: PermissionSelectByID :one
+select * from permission p where p.id = @id;
+
+-- name: PermissionSelectByCode :one
+select * from permission p where p.code = @code;
+
+-- name: PermissionUpdateByID :one
+update permission
+   set code = @code, description = @description where id = @id returning *;
+
+-- name: PermissionDeleteByID :one
+delete from permission where id = @id returning id;
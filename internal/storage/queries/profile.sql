@@ -35,6 +35,11 @@ update profile
    set avatar_url = @avatar_url
  where id = @id returning *;
 
+-- name: ProfileUpdateMagicLinkByUserID :one
+update profile
+   set enable_magic_link = @enable_magic_link
+ where user_id = @user_id returning *;
+
 -- name: ProfileIsExists :one
 select case when exists (
     select * from profile p where p.user_id = @user_id
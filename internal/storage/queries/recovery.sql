@@ -0,0 +1,21 @@
+-- name: RecoveryCodeNew :exec
+insert into recovery_code(
+    user_id, code_hash
+) values(
+    @user_id, @code_hash
+);
+
+-- name: RecoveryCodeCountUnusedByUserID :one
+select count(*) from recovery_code
+ where user_id = @user_id and used_at = '1000-01-01'::timestamp;
+
+-- name: RecoveryCodeUseByUserID :one
+update recovery_code
+   set used_at = timezone('utc', now())
+ where user_id = @user_id
+   and code_hash = @code_hash
+   and used_at = '1000-01-01'::timestamp
+ returning *;
+
+-- name: RecoveryCodeDeleteByUserID :exec
+delete from recovery_code where user_id = @user_id;
@@ -0,0 +1,25 @@
+-- name: RolePermissionNew :exec
+insert into role_permission(
+    role_id, permission_id
+) values(
+    @role_id, @permission_id
+) on conflict (role_id, permission_id) do nothing;
+
+-- name: RolePermissionSelectByRoleID :many
+select p.*
+  from permission p
+  join role_permission rp on rp.permission_id = p.id
+ where rp.role_id = @role_id
+ order by p.code;
+
+-- name: RolePermissionSelectPolicy :many
+select r.name as role, p.code as permission
+  from role_permission rp
+  join role r on r.id = rp.role_id
+  join permission p on p.id = rp.permission_id
+ order by r.name, p.code;
+
+-- name: RolePermissionDeleteByRoleIDPermissionID :one
+delete from role_permission
+ where role_id = @role_id and permission_id = @permission_id
+ returning id;
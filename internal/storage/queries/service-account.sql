@@ -0,0 +1,17 @@
+-- name: ServiceAccountNew :one
+insert into users(
+    username, password, is_checked, checked_at, is_service
+) values(
+    @username, '!', true, timezone('utc', now()), true
+) returning id, username, is_blocked, is_checked, created_at;
+
+-- name: ServiceAccountSelect :many
+select id, username, is_blocked, is_checked, created_at
+  from users u
+ where u.is_service
+ order by u.username;
+
+-- name: ServiceAccountSelectByID :one
+select id, username, is_blocked, is_checked, created_at
+  from users u
+ where u.id = @id and u.is_service;
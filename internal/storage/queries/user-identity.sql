@@ -0,0 +1,37 @@
+-- name: UserIdentityNew :one
+insert into user_identity(
+    user_id, provider, subject, email, last_used_at
+) values(
+    @user_id, @provider, @subject, @email, timezone('utc', now())
+) returning id, user_id, provider, subject, email, last_used_at, created_at;
+
+-- name: UserIdentitySelectByUserID :many
+select id, user_id, provider, subject, email, last_used_at, created_at
+  from user_identity ui
+ where ui.user_id = @user_id
+ order by ui.provider, ui.created_at;
+
+-- name: UserIdentitySelectByProviderSubject :one
+select id, user_id, provider, subject, email, last_used_at, created_at
+  from user_identity ui
+ where ui.provider = @provider and ui.subject = @subject;
+
+-- name: UserIdentityCountByUserID :one
+select count(*) from user_identity where user_id = @user_id;
+
+-- name: UserIdentityUpdateLastUsedAtByID :exec
+update user_identity
+   set email = @email, last_used_at = timezone('utc', now())
+ where id = @id;
+
+-- name: UserIdentityDeleteByUserIDID :one
+delete from user_identity
+ where user_id = @user_id and id = @id
+ returning id;
+
+-- name: UserIdentityUserNew :one
+insert into users(
+    username, password, is_checked, checked_at
+) values(
+    @username, '!', true, timezone('utc', now())
+) returning id, username, is_blocked, is_checked, created_at;
@@ -0,0 +1,23 @@
+-- name: UserRoleNew :exec
+insert into user_role(
+    user_id, role_id
+) values(
+    @user_id, @role_id
+) on conflict (user_id, role_id) do nothing;
+
+-- name: UserRoleSelectByUserID :many
+select r.*
+  from role r
+  join user_role ur on ur.role_id = r.id
+ where ur.user_id = @user_id
+ order by r.name;
+
+-- name: UserRoleDeleteByUserIDRoleID :one
+delete from user_role
+ where user_id = @user_id and role_id = @role_id
+ returning id;
+
+-- name: UserRoleSelectUserIDByRoleID :many
+select user_id
+  from user_role
+ where role_id = @role_id;
@@ -0,0 +1,35 @@
+-- name: UserSessionNew :exec
+insert into user_session(
+    user_id, family_id, user_agent, ip_address, expires_at
+) values(
+    @user_id, @family_id, @user_agent, @ip_address, @expires_at
+);
+
+-- name: UserSessionSelectByUserID :many
+select id, user_id, family_id, user_agent, ip_address, last_used_at, expires_at, created_at
+  from user_session us
+ where us.user_id = @user_id and us.expires_at > timezone('utc', now())
+ order by us.last_used_at desc;
+
+-- name: UserSessionUpdateByFamilyID :exec
+update user_session
+   set last_used_at = timezone('utc', now()),
+       expires_at = @expires_at
+ where family_id = @family_id;
+
+-- name: UserSessionDeleteByUserIDID :one
+delete from user_session
+ where user_id = @user_id and id = @id
+ returning family_id;
+
+-- name: UserSessionDeleteByFamilyID :exec
+delete from user_session where family_id = @family_id;
+
+-- name: UserSessionDeleteByUserID :exec
+delete from user_session where user_id = @user_id;
+
+-- name: UserSessionDeleteByUserIDExcept :exec
+delete from user_session where user_id = @user_id and family_id <> @family_id;
+
+-- name: UserSessionDeleteExpiredByUserID :exec
+delete from user_session where user_id = @user_id and expires_at <= timezone('utc', now());
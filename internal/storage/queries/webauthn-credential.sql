@@ -0,0 +1,29 @@
+-- name: WebauthnCredentialNew :one
+insert into webauthn_credential(
+    user_id, name, credential_id, credential
+) values(
+    @user_id, @name, @credential_id, @credential
+) returning id, user_id, name, last_used_at, created_at;
+
+-- name: WebauthnCredentialSelectByUserID :many
+select id, user_id, name, last_used_at, created_at
+  from webauthn_credential wc
+ where wc.user_id = @user_id
+ order by wc.created_at;
+
+-- name: WebauthnCredentialSelectCredentialByUserID :many
+select credential from webauthn_credential where user_id = @user_id;
+
+-- name: WebauthnCredentialCountByUserID :one
+select count(*) from webauthn_credential where user_id = @user_id;
+
+-- name: WebauthnCredentialUpdateByCredentialID :exec
+update webauthn_credential
+   set credential = @credential,
+       last_used_at = timezone('utc', now())
+ where user_id = @user_id and credential_id = @credential_id;
+
+-- name: WebauthnCredentialDeleteByUserIDID :one
+delete from webauthn_credential
+ where user_id = @user_id and id = @id
+ returning id;
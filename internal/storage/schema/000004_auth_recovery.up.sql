@@ -0,0 +1,19 @@
+--
+-- Entity recovery_code
+--
+create table recovery_code (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    code_hash       varchar(64)     not null,
+    used_at         timestamp       not null default '1000-01-01'::timestamp,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create index recovery_code_user_id on recovery_code(user_id);
+
+create unique index recovery_code_user_id_code_hash_unq on recovery_code(user_id, code_hash);
+
+create trigger recovery_code_updated_at
+	before update on recovery_code for each row
+	execute procedure trigger_updated_at();
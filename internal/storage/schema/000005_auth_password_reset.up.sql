@@ -0,0 +1,18 @@
+--
+-- Entity password_reset
+--
+create table password_reset (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    token_hash      varchar(64)     not null unique,
+    expires_at      timestamp       not null,
+    used_at         timestamp       not null default '1000-01-01'::timestamp,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create index password_reset_user_id on password_reset(user_id);
+
+create trigger password_reset_updated_at
+	before update on password_reset for each row
+	execute procedure trigger_updated_at();
@@ -0,0 +1,22 @@
+--
+-- Entity user_role
+--
+create table user_role (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    role_id         varchar(32)     not null references role(id) on delete cascade,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index user_role_user_id_role_id_unq on user_role(user_id, role_id);
+create index user_role_role_id on user_role(role_id);
+
+create trigger user_role_updated_at
+	before update on user_role for each row
+	execute procedure trigger_updated_at();
+
+insert into user_role(user_id, role_id)
+    select u.id, r.id from users u, role r where u.username = 'sys' and r.name = 'SYS';
+insert into user_role(user_id, role_id)
+    select u.id, r.id from users u, role r where u.username = 'admin' and r.name = 'ADMIN';
@@ -0,0 +1,2 @@
+drop table if exists role_permission;
+drop table if exists permission;
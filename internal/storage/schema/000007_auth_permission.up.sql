@@ -0,0 +1,68 @@
+--
+-- Entity permission
+--
+create table permission (
+    id              varchar(32)     not null default xid() primary key,
+    code            varchar(64)     not null,
+    description     varchar(255)    not null default '',
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index permission_code_unq on permission(code);
+
+create trigger permission_updated_at
+	before update on permission for each row
+	execute procedure trigger_updated_at();
+
+insert into permission(code, description) values
+    ('user:read',        'List and read user accounts'),
+    ('user:write',       'Update user accounts'),
+    ('user:block',       'Block and unblock user accounts'),
+    ('user:delete',      'Delete user accounts'),
+    ('role:read',        'Read role assignments'),
+    ('role:write',       'Maintain the role catalog'),
+    ('role:assign',      'Grant and revoke user roles'),
+    ('permission:read',  'Read the permission catalog and role policy'),
+    ('permission:write', 'Maintain the permission catalog and role policy'),
+    ('country:write',    'Maintain the country dictionary'),
+    ('currency:write',   'Maintain the currency dictionary');
+--
+-- Entity role_permission
+--
+create table role_permission (
+    id              varchar(32)     not null default xid() primary key,
+    role_id         varchar(32)     not null references role(id) on delete cascade,
+    permission_id   varchar(32)     not null references permission(id) on delete cascade,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index role_permission_role_id_permission_id_unq on role_permission(role_id, permission_id);
+create index role_permission_permission_id on role_permission(permission_id);
+
+create trigger role_permission_updated_at
+	before update on role_permission for each row
+	execute procedure trigger_updated_at();
+
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'ADMIN' and p.code in (
+        'user:read', 'user:write', 'user:block', 'user:delete', 'role:read', 'role:assign',
+        'permission:read', 'country:write', 'currency:write'
+     );
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'AUDITOR' and p.code in ('user:read', 'role:read', 'permission:read');
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'MANAGER' and p.code in ('user:read', 'user:block');
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'REPORTER' and p.code in ('user:read');
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'EDITOR' and p.code in ('country:write');
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'FINANCIER' and p.code in ('currency:write');
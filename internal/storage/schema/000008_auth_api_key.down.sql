@@ -0,0 +1,5 @@
+delete from permission where code in ('apikey:read', 'apikey:write');
+
+drop table if exists api_key;
+
+alter table users drop column if exists is_service;
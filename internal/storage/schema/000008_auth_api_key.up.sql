@@ -0,0 +1,34 @@
+--
+-- Service accounts are regular users flagged as such, they have no usable password
+--
+alter table users add column is_service bool not null default false;
+--
+-- Entity api_key
+--
+create table api_key (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    name            varchar(255)    not null,
+    prefix          varchar(16)     not null,
+    key_hash        varchar(64)     not null,
+    scopes          text[]          not null default '{}',
+    expires_at      timestamp       not null default '1000-01-01'::timestamp,
+    last_used_at    timestamp       not null default '1000-01-01'::timestamp,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index api_key_prefix_unq on api_key(prefix);
+create index api_key_user_id on api_key(user_id);
+
+create trigger api_key_updated_at
+	before update on api_key for each row
+	execute procedure trigger_updated_at();
+
+insert into permission(code, description) values
+    ('apikey:read',  'List service accounts and their api keys'),
+    ('apikey:write', 'Create service accounts, issue and revoke api keys');
+
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'ADMIN' and p.code in ('apikey:read', 'apikey:write');
@@ -0,0 +1,4 @@
+delete from permission where code in ('oauth:read', 'oauth:write');
+
+drop table if exists oauth_consent;
+drop table if exists oauth_client;
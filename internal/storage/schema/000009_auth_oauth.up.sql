@@ -0,0 +1,48 @@
+--
+-- Entity oauth_client
+--
+-- Public clients (SPA, mobile) have no secret, confidential clients keep a hashed
+-- secret. Clients linked to a service account may use the client_credentials grant.
+--
+create table oauth_client (
+    id              varchar(32)     not null default xid() primary key,
+    name            varchar(255)    not null,
+    secret_hash     varchar(64)     not null default '',
+    is_public       bool            not null default false,
+    redirect_uris   text[]          not null default '{}',
+    grant_types     text[]          not null default '{}',
+    scopes          text[]          not null default '{}',
+    user_id         varchar(32)     null references users(id) on delete cascade,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create trigger oauth_client_updated_at
+	before update on oauth_client for each row
+	execute procedure trigger_updated_at();
+--
+-- Entity oauth_consent
+--
+create table oauth_consent (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    client_id       varchar(32)     not null references oauth_client(id) on delete cascade,
+    scopes          text[]          not null default '{}',
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index oauth_consent_user_id_client_id_unq on oauth_consent(user_id, client_id);
+create index oauth_consent_client_id on oauth_consent(client_id);
+
+create trigger oauth_consent_updated_at
+	before update on oauth_consent for each row
+	execute procedure trigger_updated_at();
+
+insert into permission(code, description) values
+    ('oauth:read',  'List the registered OAuth clients'),
+    ('oauth:write', 'Register and remove OAuth clients');
+
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'ADMIN' and p.code in ('oauth:read', 'oauth:write');
@@ -0,0 +1,23 @@
+--
+-- Entity user_identity
+--
+-- Identities of the external OpenID Connect providers linked to the user, the
+-- subject is unique per provider and one user may link several identities.
+--
+create table user_identity (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    provider        varchar(64)     not null,
+    subject         varchar(255)    not null,
+    email           varchar(255)    not null default '',
+    last_used_at    timestamp       not null default '1000-01-01'::timestamp,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index user_identity_provider_subject_unq on user_identity(provider, subject);
+create index user_identity_user_id on user_identity(user_id);
+
+create trigger user_identity_updated_at
+	before update on user_identity for each row
+	execute procedure trigger_updated_at();
@@ -0,0 +1,3 @@
+drop table if exists magic_link;
+
+alter table profile drop column if exists enable_magic_link;
@@ -0,0 +1,23 @@
+--
+-- Users may opt out of the magic link sign-in, the feature itself is switched
+-- on by the auth-magic-link-enabled flag
+--
+alter table profile add column enable_magic_link bool not null default true;
+--
+-- Entity magic_link
+--
+create table magic_link (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    token_hash      varchar(64)     not null unique,
+    expires_at      timestamp       not null,
+    used_at         timestamp       not null default '1000-01-01'::timestamp,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create index magic_link_user_id on magic_link(user_id);
+
+create trigger magic_link_updated_at
+	before update on magic_link for each row
+	execute procedure trigger_updated_at();
@@ -0,0 +1,23 @@
+--
+-- Entity webauthn_credential
+--
+-- Passkeys registered by the user, the credential keeps the public key, the
+-- signature counter and the flags as serialized by the webauthn library.
+--
+create table webauthn_credential (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    name            varchar(64)     not null default '',
+    credential_id   bytea           not null,
+    credential      jsonb           not null,
+    last_used_at    timestamp       not null default '1000-01-01'::timestamp,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index webauthn_credential_credential_id_unq on webauthn_credential(credential_id);
+create index webauthn_credential_user_id on webauthn_credential(user_id);
+
+create trigger webauthn_credential_updated_at
+	before update on webauthn_credential for each row
+	execute procedure trigger_updated_at();
@@ -0,0 +1 @@
+delete from permission where code in ('token:introspect');
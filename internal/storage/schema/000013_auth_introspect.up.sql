@@ -0,0 +1,10 @@
+--
+-- Token introspection (RFC 7662) for the services checking platform tokens
+-- centrally, grant the permission to the role of their service accounts.
+--
+insert into permission(code, description) values
+    ('token:introspect', 'Introspect the platform tokens');
+
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'ADMIN' and p.code in ('token:introspect');
@@ -0,0 +1,3 @@
+delete from permission where code in ('session:read', 'session:revoke');
+
+drop table if exists user_session;
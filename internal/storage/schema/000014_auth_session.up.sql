@@ -0,0 +1,35 @@
+--
+-- Entity user_session
+--
+-- Signed-in devices, one per refresh token family. The session lives as long
+-- as its family is refreshed and is revoked together with the family tokens.
+--
+create table user_session (
+    id              varchar(32)     not null default xid() primary key,
+    user_id         varchar(32)     not null references users(id) on delete cascade,
+    family_id       varchar(64)     not null,
+    user_agent      varchar(512)    not null default '',
+    ip_address      varchar(64)     not null default '',
+    last_used_at    timestamp       not null default timezone('utc', now()),
+    expires_at      timestamp       not null,
+    created_at      timestamp       not null default timezone('utc', now()),
+    updated_at      timestamp       not null default '1000-01-01'::timestamp
+);
+
+create unique index user_session_family_id_unq on user_session(family_id);
+create index user_session_user_id on user_session(user_id);
+
+create trigger user_session_updated_at
+	before update on user_session for each row
+	execute procedure trigger_updated_at();
+
+--
+-- Admins list and revoke the sessions of any user
+--
+insert into permission(code, description) values
+    ('session:read',   'List the sessions of any user'),
+    ('session:revoke', 'Revoke the sessions of any user');
+
+insert into role_permission(role_id, permission_id)
+    select r.id, p.id from role r, permission p
+     where r.name = 'ADMIN' and p.code in ('session:read', 'session:revoke');